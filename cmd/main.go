@@ -9,10 +9,10 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/egor_lukyanovich/moon_test_application/internal/handlers"
 	"github.com/egor_lukyanovich/moon_test_application/pkg/app"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/mail"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/ratelimit"
 	"github.com/egor_lukyanovich/moon_test_application/pkg/routing"
-	"github.com/go-chi/chi/v5"
 )
 
 func main() {
@@ -27,38 +27,33 @@ func main() {
 		log.Println("Database and Redis connections closed")
 	}()
 
-	r := routing.NewRouter()
-
-	authH := routing.NewAuthHandlers(storage.Queries)
-	teamH := handlers.NewTeamHandlers(storage.Queries, storage.DB)
-	taskH := handlers.NewTaskHandlers(storage.Queries, storage.DB, storage.Redis)
-	historyH := handlers.NewHistoryHandlers(storage.Queries)
-	statsH := handlers.NewStatsHandlers(storage.Queries)
+	keys, err := routing.LoadKeySet()
+	if err != nil {
+		log.Fatalf("JWT key configuration failed: %v", err)
+	}
 
-	r.Route("/api/v1", func(api chi.Router) {
-		api.Group(func(public chi.Router) {
-			public.Post("/register", authH.Register)
-			public.Post("/login", authH.Login)
-		})
+	denylist := routing.NewRedisDenylist(storage.Redis)
 
-		api.Group(func(protected chi.Router) {
-			protected.Use(routing.AuthMiddleware)
+	limiter, err := ratelimit.FromEnv(storage.Redis)
+	if err != nil {
+		log.Fatalf("Rate limiter configuration failed: %v", err)
+	}
+	authLimiter := routing.NewAuthLimiter(limiter, routing.DefaultAuthLimits())
 
-			protected.Post("/teams", teamH.CreateTeam)
-			protected.Get("/teams", teamH.ListTeams)
-			protected.Post("/teams/{id}/invite", teamH.InviteToTeam)
+	buckets, err := ratelimit.BucketsFromEnv(storage.Redis)
+	if err != nil {
+		log.Fatalf("Rate limiter configuration failed: %v", err)
+	}
+	userLimiter := routing.NewUserLimiter(buckets, routing.DefaultAPILimits())
 
-			protected.Post("/tasks", taskH.CreateTask)
-			protected.Get("/tasks", taskH.ListTasks)
-			protected.Put("/tasks/{id}", taskH.UpdateTask)
+	mailer, err := mail.FromEnv()
+	if err != nil {
+		log.Fatalf("Mailer configuration failed: %v", err)
+	}
 
-			protected.Get("/tasks/{id}/history", historyH.GetTaskHistory)
+	resetH := routing.NewPasswordResetHandlers(storage.Queries, storage.DB, mailer, os.Getenv("PASSWORD_RESET_URL"))
 
-			protected.Get("/stats/teams", statsH.GetTeamStats)
-			protected.Get("/stats/top-users", statsH.GetTopUsers)
-			protected.Get("/stats/invalid-tasks", statsH.GetInvalidTasks)
-		})
-	})
+	r := newRouter(storage, keys, denylist, authLimiter, userLimiter, resetH)
 
 	srv := &http.Server{
 		Addr:    ":" + port,
@@ -84,5 +79,9 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Reset requests answered before shutdown still need the database and
+	// the mailer, so they finish before the deferred close runs.
+	resetH.Wait()
+
 	log.Println("Server exiting gracefully")
 }
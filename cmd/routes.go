@@ -0,0 +1,93 @@
+package main
+
+import (
+	"github.com/egor_lukyanovich/moon_test_application/internal/handlers"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/app"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/routing"
+	"github.com/go-chi/chi/v5"
+)
+
+// newRouter builds the full route table. It lives apart from main so tests can
+// exercise exactly the routes the server registers.
+func newRouter(storage *app.Storage, keys *routing.KeySet, denylist routing.TokenDenylist, authLimiter *routing.AuthLimiter, userLimiter *routing.UserLimiter, resetH *routing.PasswordResetHandlers) *chi.Mux {
+	r := routing.NewRouter()
+
+	authH := routing.NewAuthHandlers(storage.Queries, storage.DB, keys, denylist)
+	teamH := handlers.NewTeamHandlers(storage.Queries, storage.DB, storage.Redis)
+	taskH := handlers.NewTaskHandlers(storage.Queries, storage.DB, storage.Redis)
+	memberH := handlers.NewMemberHandlers(storage.Queries, storage.DB, storage.Redis)
+	historyH := handlers.NewHistoryHandlers(storage.Queries)
+	commentH := handlers.NewCommentHandlers(storage.Queries, storage.DB)
+	statsH := handlers.NewStatsHandlers(storage.Queries)
+	userH := handlers.NewUserHandlers(storage.Queries, storage.DB)
+
+	r.Get("/.well-known/jwks.json", routing.JWKSHandler(keys))
+
+	r.Route("/api/v1", func(api chi.Router) {
+		api.Group(func(public chi.Router) {
+			public.Group(func(limited chi.Router) {
+				limited.Use(authLimiter.RateLimit)
+
+				limited.Post("/register", authH.Register)
+				limited.With(authLimiter.Lockout).Post("/login", authH.Login)
+				limited.Post("/password-reset/request", resetH.RequestReset)
+			})
+
+			public.Post("/refresh", authH.Refresh)
+			public.Post("/password-reset/confirm", resetH.ConfirmReset)
+		})
+
+		api.Group(func(protected chi.Router) {
+			protected.Use(routing.AuthMiddleware(keys, denylist))
+			protected.Use(userLimiter.Limit("api"))
+
+			listLimit := userLimiter.Limit("tasks:list")
+			statsLimit := userLimiter.Limit("stats")
+
+			protected.Post("/logout", authH.Logout)
+
+			protected.Get("/me", userH.GetMe)
+			protected.With(authLimiter.PasswordLockout).Patch("/me", userH.UpdateMe)
+			protected.Get("/users/{id}", userH.GetUser)
+
+			protected.Post("/teams", teamH.CreateTeam)
+			protected.Get("/teams", teamH.ListTeams)
+			protected.Patch("/teams/{id}", teamH.UpdateTeam)
+			protected.Delete("/teams/{id}", teamH.DeleteTeam)
+			protected.Post("/teams/{id}/transfer-ownership", teamH.TransferOwnership)
+			protected.Post("/teams/{id}/invite", teamH.InviteToTeam)
+			protected.Get("/invitations", teamH.ListInvitations)
+			protected.Post("/invitations/{id}/accept", teamH.AcceptInvitation)
+			protected.Post("/invitations/{id}/decline", teamH.DeclineInvitation)
+			protected.Get("/teams/{id}/members", memberH.ListMembers)
+			protected.Patch("/teams/{id}/members/{userID}", memberH.UpdateMemberRole)
+			protected.Delete("/teams/{id}/members/{userID}", memberH.RemoveMember)
+			protected.Post("/teams/{id}/leave", memberH.LeaveTeam)
+			protected.Post("/teams/{id}/invalid-tasks/repair", taskH.RepairInvalidTasks)
+			protected.Get("/teams/{id}/workflow", teamH.GetWorkflow)
+			protected.Put("/teams/{id}/workflow", teamH.UpdateWorkflow)
+			protected.With(listLimit).Get("/teams/{id}/board", taskH.Board)
+
+			protected.Post("/tasks", taskH.CreateTask)
+			protected.With(listLimit).Get("/tasks", taskH.ListTasks)
+			protected.With(listLimit).Get("/me/tasks", taskH.MyTasks)
+			protected.Put("/tasks/{id}", taskH.UpdateTask)
+			protected.Patch("/tasks/{id}", taskH.PatchTask)
+			protected.Delete("/tasks/{id}", taskH.DeleteTask)
+			protected.Post("/tasks/{id}/move", taskH.MoveTask)
+
+			protected.Get("/tasks/{id}/history", historyH.GetTaskHistory)
+
+			protected.Post("/tasks/{id}/comments", commentH.CreateComment)
+			protected.Get("/tasks/{id}/comments", commentH.ListComments)
+			protected.Put("/tasks/{id}/comments/{commentID}", commentH.UpdateComment)
+			protected.Delete("/tasks/{id}/comments/{commentID}", commentH.DeleteComment)
+
+			protected.With(statsLimit).Get("/stats/teams", statsH.GetTeamStats)
+			protected.With(statsLimit).Get("/stats/top-users", statsH.GetTopUsers)
+			protected.With(statsLimit).Get("/stats/invalid-tasks", statsH.GetInvalidTasks)
+		})
+	})
+
+	return r
+}
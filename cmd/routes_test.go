@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/app"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/mail"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/ratelimit"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/routing"
+	"github.com/go-chi/chi/v5"
+	_ "github.com/go-sql-driver/mysql"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/testcontainers/testcontainers-go/modules/mysql"
+	tc_redis "github.com/testcontainers/testcontainers-go/modules/redis"
+)
+
+// victimMarker is part of every name the victim creates, so leaks are easy to spot.
+const victimMarker = "victim-secret"
+
+var publicRoutes = map[string]bool{
+	"GET /health":                         true,
+	"GET /.well-known/jwks.json":          true,
+	"POST /api/v1/register":               true,
+	"POST /api/v1/login":                  true,
+	"POST /api/v1/refresh":                true,
+	"POST /api/v1/password-reset/request": true,
+	"POST /api/v1/password-reset/confirm": true,
+}
+
+// applyMigrations runs the goose Up sections of sql/schema in order, so the
+// suite runs against the real schema rather than a hand-written copy.
+func applyMigrations(t *testing.T, database *sql.DB) {
+	files, err := filepath.Glob("../sql/schema/*.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("failed to find migrations: %v", err)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		up := strings.SplitN(string(data), "-- +goose Down", 2)[0]
+		up = strings.Replace(up, "-- +goose Up", "", 1)
+		if _, err := database.Exec(up); err != nil {
+			t.Fatalf("failed to apply %s: %v", file, err)
+		}
+	}
+}
+
+func setupRouter(t *testing.T) (*chi.Mux, *sql.DB, func()) {
+	ctx := context.Background()
+
+	mysqlContainer, err := mysql.Run(ctx,
+		"mysql:8.0",
+		mysql.WithDatabase("testdb"),
+		mysql.WithUsername("user"),
+		mysql.WithPassword("pass"),
+	)
+	if err != nil {
+		t.Fatalf("failed to start mysql: %v", err)
+	}
+
+	connStr, err := mysqlContainer.ConnectionString(ctx, "multiStatements=true", "parseTime=true")
+	if err != nil {
+		t.Fatalf("failed to get connection string: %v", err)
+	}
+
+	database, err := sql.Open("mysql", connStr)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	applyMigrations(t, database)
+
+	redisContainer, err := tc_redis.Run(ctx, "redis:7")
+	if err != nil {
+		t.Fatalf("failed to start redis container: %v", err)
+	}
+	uri, err := redisContainer.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("failed to get redis uri: %v", err)
+	}
+	rdb := goredis.NewClient(&goredis.Options{Addr: uri[8:]})
+
+	keys, err := routing.NewKeySet("test", map[string][]byte{"test": []byte(strings.Repeat("k", 32))})
+	if err != nil {
+		t.Fatalf("failed to create key set: %v", err)
+	}
+
+	// The walk logs in far more often than real clients, so only the lockout
+	// keeps its production setting.
+	limits := routing.DefaultAuthLimits()
+	limits.PerIP, limits.PerEmail = 1000, 1000
+	authLimiter := routing.NewAuthLimiter(ratelimit.NewRedis(rdb), limits)
+	userLimiter := routing.NewUserLimiter(ratelimit.NewRedisBuckets(rdb), routing.DefaultAPILimits())
+
+	storage := &app.Storage{Queries: db.New(database), DB: database, Redis: rdb}
+	resetH := routing.NewPasswordResetHandlers(storage.Queries, database, mail.NewLogMailer(io.Discard, "test@example.com"), "")
+	r := newRouter(storage, keys, routing.NewRedisDenylist(rdb), authLimiter, userLimiter, resetH)
+
+	cleanup := func() {
+		rdb.Close()
+		redisContainer.Terminate(ctx)
+		database.Close()
+		mysqlContainer.Terminate(ctx)
+	}
+	return r, database, cleanup
+}
+
+type apiClient struct {
+	t *testing.T
+	r http.Handler
+}
+
+func (c apiClient) do(method, url, token string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, url, &buf)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	c.r.ServeHTTP(rr, req)
+	return rr
+}
+
+// signUp registers a user and returns its id and a function that logs in
+// again, so each request can use a fresh session (POST /logout revokes one).
+func (c apiClient) signUp(email string) (int64, func() string) {
+	creds := map[string]string{"email": email, "password": "superpassword"}
+	rr := c.do(http.MethodPost, "/api/v1/register", "", creds)
+	if rr.Code != http.StatusCreated {
+		c.t.Fatalf("register %s failed: %d %s", email, rr.Code, rr.Body.String())
+	}
+	var reg struct {
+		ID int64 `json:"id"`
+	}
+	json.NewDecoder(rr.Body).Decode(&reg)
+
+	return reg.ID, func() string {
+		rr := c.do(http.MethodPost, "/api/v1/login", "", creds)
+		var tokens map[string]string
+		json.NewDecoder(rr.Body).Decode(&tokens)
+		if tokens["token"] == "" {
+			c.t.Fatalf("login %s failed: %d %s", email, rr.Code, rr.Body.String())
+		}
+		return tokens["token"]
+	}
+}
+
+func (c apiClient) create(url, token string, body interface{}, idField string) int64 {
+	rr := c.do(http.MethodPost, url, token, body)
+	if rr.Code != http.StatusCreated {
+		c.t.Fatalf("POST %s failed: %d %s", url, rr.Code, rr.Body.String())
+	}
+	// Responses may carry more than the id, e.g. an invitation's email.
+	var resp map[string]json.RawMessage
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		c.t.Fatalf("POST %s returned invalid json: %v", url, err)
+	}
+	var id int64
+	if err := json.Unmarshal(resp[idField], &id); err != nil || id == 0 {
+		c.t.Fatalf("POST %s returned no %s: %v", url, idField, err)
+	}
+	return id
+}
+
+type crossTeamCase struct {
+	path string
+	body interface{}
+	// allowed routes are not tied to a foreign resource and must succeed,
+	// but without showing anything of the victim.
+	allowed bool
+}
+
+func TestCrossTeamAccess(t *testing.T) {
+	r, database, cleanup := setupRouter(t)
+	defer cleanup()
+
+	c := apiClient{t: t, r: r}
+
+	victimID, victimLogin := c.signUp("victim@example.com")
+	attackerID, attackerLogin := c.signUp("mallory@example.com")
+	c.signUp("invitee@example.com")
+
+	victim := victimLogin()
+	teamID := c.create("/api/v1/teams", victim, map[string]string{"name": victimMarker + " team"}, "team_id")
+	taskID := c.create("/api/v1/tasks", victim, map[string]interface{}{
+		"title": victimMarker + " task", "status": "todo", "team_id": teamID, "assignee_id": victimID,
+	}, "task_id")
+	commentID := c.create("/api/v1/tasks/"+strconv.FormatInt(taskID, 10)+"/comments", victim,
+		map[string]string{"content": victimMarker + " comment"}, "comment_id")
+	invitationID := c.create("/api/v1/teams/"+strconv.FormatInt(teamID, 10)+"/invite", victim,
+		map[string]string{"email": "invitee@example.com", "role": "member"}, "invitation_id")
+
+	// The attacker owns a team of their own, so owner-only checks must be
+	// tied to the team in the URL and not to "owner somewhere".
+	c.create("/api/v1/teams", attackerLogin(), map[string]string{"name": "Mallory team"}, "team_id")
+
+	team := "/api/v1/teams/" + strconv.FormatInt(teamID, 10)
+	task := "/api/v1/tasks/" + strconv.FormatInt(taskID, 10)
+	comment := task + "/comments/" + strconv.FormatInt(commentID, 10)
+	invitation := "/api/v1/invitations/" + strconv.FormatInt(invitationID, 10)
+	teamQuery := "?team_id=" + strconv.FormatInt(teamID, 10)
+	victimUser := strconv.FormatInt(victimID, 10)
+	taskBody := map[string]interface{}{"title": "pwned", "status": "done", "team_id": teamID}
+
+	cases := map[string]crossTeamCase{
+		"POST /api/v1/logout":                            {path: "/api/v1/logout", allowed: true},
+		"GET /api/v1/me":                                 {path: "/api/v1/me", allowed: true},
+		"PATCH /api/v1/me":                               {path: "/api/v1/me", body: map[string]string{"display_name": "Mallory"}, allowed: true},
+		"GET /api/v1/users/{id}":                         {path: "/api/v1/users/" + victimUser},
+		"POST /api/v1/teams":                             {path: "/api/v1/teams", body: map[string]string{"name": "Another team"}, allowed: true},
+		"GET /api/v1/teams":                              {path: "/api/v1/teams", allowed: true},
+		"PATCH /api/v1/teams/{id}":                       {path: team, body: map[string]string{"name": "pwned"}},
+		"DELETE /api/v1/teams/{id}":                      {path: team},
+		"POST /api/v1/teams/{id}/transfer-ownership":     {path: team + "/transfer-ownership", body: map[string]int64{"user_id": attackerID}},
+		"POST /api/v1/teams/{id}/invite":                 {path: team + "/invite", body: map[string]string{"email": "mallory2@example.com", "role": "member"}},
+		"GET /api/v1/invitations":                        {path: "/api/v1/invitations", allowed: true},
+		"POST /api/v1/invitations/{id}/accept":           {path: invitation + "/accept"},
+		"POST /api/v1/invitations/{id}/decline":          {path: invitation + "/decline"},
+		"GET /api/v1/teams/{id}/members":                 {path: team + "/members"},
+		"PATCH /api/v1/teams/{id}/members/{userID}":      {path: team + "/members/" + victimUser, body: map[string]string{"role": "member"}},
+		"DELETE /api/v1/teams/{id}/members/{userID}":     {path: team + "/members/" + victimUser},
+		"POST /api/v1/teams/{id}/leave":                  {path: team + "/leave"},
+		"POST /api/v1/teams/{id}/invalid-tasks/repair":   {path: team + "/invalid-tasks/repair", body: map[string]string{"strategy": "unassign"}},
+		"GET /api/v1/teams/{id}/workflow":                {path: team + "/workflow"},
+		"PUT /api/v1/teams/{id}/workflow":                {path: team + "/workflow", body: map[string][]string{"statuses": {"todo", "done"}}},
+		"GET /api/v1/teams/{id}/board":                   {path: team + "/board"},
+		"POST /api/v1/tasks":                             {path: "/api/v1/tasks", body: taskBody},
+		"GET /api/v1/tasks":                              {path: "/api/v1/tasks" + teamQuery},
+		"GET /api/v1/me/tasks":                           {path: "/api/v1/me/tasks", allowed: true},
+		"PUT /api/v1/tasks/{id}":                         {path: task, body: taskBody},
+		"PATCH /api/v1/tasks/{id}":                       {path: task, body: map[string]string{"title": "pwned"}},
+		"DELETE /api/v1/tasks/{id}":                      {path: task},
+		"POST /api/v1/tasks/{id}/move":                   {path: task + "/move", body: map[string]interface{}{"status": "done", "position": 0}},
+		"GET /api/v1/tasks/{id}/history":                 {path: task + "/history"},
+		"POST /api/v1/tasks/{id}/comments":               {path: task + "/comments", body: map[string]string{"content": "pwned"}},
+		"GET /api/v1/tasks/{id}/comments":                {path: task + "/comments"},
+		"PUT /api/v1/tasks/{id}/comments/{commentID}":    {path: comment, body: map[string]string{"content": "pwned"}},
+		"DELETE /api/v1/tasks/{id}/comments/{commentID}": {path: comment},
+		"GET /api/v1/stats/teams":                        {path: "/api/v1/stats/teams" + teamQuery},
+		"GET /api/v1/stats/top-users":                    {path: "/api/v1/stats/top-users" + teamQuery},
+		"GET /api/v1/stats/invalid-tasks":                {path: "/api/v1/stats/invalid-tasks" + teamQuery},
+	}
+
+	// Warm the task list cache as the victim: a cached page must not be served
+	// to someone who is not a member.
+	if rr := c.do(http.MethodGet, "/api/v1/tasks"+teamQuery, victim, nil); rr.Code != http.StatusOK {
+		t.Fatalf("expected victim to list tasks, got %d", rr.Code)
+	}
+
+	var routes []string
+	chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes = append(routes, method+" "+route)
+		return nil
+	})
+
+	for _, route := range routes {
+		if publicRoutes[route] {
+			continue
+		}
+
+		tc, ok := cases[route]
+		if !ok {
+			t.Errorf("%s has no cross-team case; add one when registering a protected route", route)
+			continue
+		}
+		method := strings.SplitN(route, " ", 2)[0]
+
+		if rr := c.do(method, tc.path, "", tc.body); rr.Code != http.StatusUnauthorized {
+			t.Errorf("%s without a token: expected 401, got %d", route, rr.Code)
+		}
+
+		rr := c.do(method, tc.path, attackerLogin(), tc.body)
+		switch {
+		case tc.allowed && rr.Code >= 300:
+			t.Errorf("%s: expected success for the caller's own scope, got %d: %s", route, rr.Code, rr.Body.String())
+		case !tc.allowed && rr.Code != http.StatusForbidden && rr.Code != http.StatusNotFound:
+			t.Errorf("%s on a foreign team: expected 403 or 404, got %d: %s", route, rr.Code, rr.Body.String())
+		}
+		if strings.Contains(rr.Body.String(), victimMarker) || strings.Contains(rr.Body.String(), "victim@example.com") {
+			t.Errorf("%s leaked victim data: %s", route, rr.Body.String())
+		}
+	}
+
+	queries := db.New(database)
+	ctx := context.Background()
+
+	if teams, _ := queries.ListUserTeams(ctx, victimID); len(teams) != 1 || teams[0].Name != victimMarker+" team" || teams[0].Role != "owner" {
+		t.Errorf("expected victim team to be untouched, got %+v", teams)
+	}
+	if members, _ := queries.ListTeamMembers(ctx, teamID); len(members) != 1 {
+		t.Errorf("expected the victim to stay the only member, got %+v", members)
+	}
+	if got, err := queries.GetTaskByID(ctx, taskID); err != nil || got.Title != victimMarker+" task" || got.Status != "todo" || got.AssigneeID.Int64 != victimID {
+		t.Errorf("expected victim task to be untouched, got %+v (%v)", got, err)
+	}
+	var content string
+	if err := database.QueryRow("SELECT content FROM task_comments WHERE id = ?", commentID).Scan(&content); err != nil || content != victimMarker+" comment" {
+		t.Errorf("expected victim comment to be untouched, got %q (%v)", content, err)
+	}
+	if inv, err := queries.GetInvitationByID(ctx, invitationID); err != nil || inv.Status != db.TeamInvitationsStatusPending {
+		t.Errorf("expected invitation to stay pending, got %+v (%v)", inv, err)
+	}
+}
@@ -8,6 +8,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 const findInvalidTasks = `-- name: FindInvalidTasks :many
@@ -16,8 +17,15 @@ FROM tasks t
 LEFT JOIN team_members tm ON t.team_id = tm.team_id AND t.assignee_id = tm.user_id
 WHERE t.assignee_id IS NOT NULL 
   AND tm.user_id IS NULL
+  AND (? IS NULL OR t.team_id IN (SELECT team_id FROM team_members WHERE user_id = ?))
+  AND (? IS NULL OR t.team_id = ?)
 `
 
+type FindInvalidTasksParams struct {
+	MemberID sql.NullInt64
+	TeamID   sql.NullInt64
+}
+
 type FindInvalidTasksRow struct {
 	ID         int64
 	Title      string
@@ -25,8 +33,13 @@ type FindInvalidTasksRow struct {
 	AssigneeID sql.NullInt64
 }
 
-func (q *Queries) FindInvalidTasks(ctx context.Context) ([]FindInvalidTasksRow, error) {
-	rows, err := q.db.QueryContext(ctx, findInvalidTasks)
+func (q *Queries) FindInvalidTasks(ctx context.Context, arg FindInvalidTasksParams) ([]FindInvalidTasksRow, error) {
+	rows, err := q.db.QueryContext(ctx, findInvalidTasks,
+		arg.MemberID,
+		arg.MemberID,
+		arg.TeamID,
+		arg.TeamID,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -55,25 +68,44 @@ func (q *Queries) FindInvalidTasks(ctx context.Context) ([]FindInvalidTasksRow,
 
 const getTeamStats = `-- name: GetTeamStats :many
 SELECT 
+    t.id AS team_id,
     t.name AS team_name,
     COUNT(DISTINCT tm.user_id) AS members_count,
-    COUNT(DISTINCT task.id) AS done_tasks_last_7_days
+    COUNT(DISTINCT task.id) AS done_tasks
 FROM teams t
 LEFT JOIN team_members tm ON t.id = tm.team_id
 LEFT JOIN tasks task ON t.id = task.team_id 
     AND task.status = 'done' 
-    AND task.updated_at >= NOW() - INTERVAL 7 DAY
+    AND task.updated_at >= ?
+    AND task.updated_at < ?
+WHERE (? IS NULL OR t.id IN (SELECT team_id FROM team_members WHERE user_id = ?))
+    AND (? IS NULL OR t.id = ?)
 GROUP BY t.id, t.name
 `
 
+type GetTeamStatsParams struct {
+	FromDate time.Time
+	ToDate   time.Time
+	MemberID sql.NullInt64
+	TeamID   sql.NullInt64
+}
+
 type GetTeamStatsRow struct {
-	TeamName           string
-	MembersCount       int64
-	DoneTasksLast7Days int64
+	TeamID       int64
+	TeamName     string
+	MembersCount int64
+	DoneTasks    int64
 }
 
-func (q *Queries) GetTeamStats(ctx context.Context) ([]GetTeamStatsRow, error) {
-	rows, err := q.db.QueryContext(ctx, getTeamStats)
+func (q *Queries) GetTeamStats(ctx context.Context, arg GetTeamStatsParams) ([]GetTeamStatsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTeamStats,
+		arg.FromDate,
+		arg.ToDate,
+		arg.MemberID,
+		arg.MemberID,
+		arg.TeamID,
+		arg.TeamID,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -81,7 +113,175 @@ func (q *Queries) GetTeamStats(ctx context.Context) ([]GetTeamStatsRow, error) {
 	var items []GetTeamStatsRow
 	for rows.Next() {
 		var i GetTeamStatsRow
-		if err := rows.Scan(&i.TeamName, &i.MembersCount, &i.DoneTasksLast7Days); err != nil {
+		if err := rows.Scan(
+			&i.TeamID,
+			&i.TeamName,
+			&i.MembersCount,
+			&i.DoneTasks,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTopAssigneesPerTeam = `-- name: GetTopAssigneesPerTeam :many
+SELECT 
+    final_tab.team_id,
+    final_tab.user_id,
+    final_tab.task_count,
+    final_tab.rank_num
+FROM (
+    SELECT 
+        user_counts.team_id,
+        user_counts.user_id,
+        user_counts.task_count,
+        DENSE_RANK() OVER (PARTITION BY user_counts.team_id ORDER BY user_counts.task_count DESC) as rank_num
+    FROM (
+        SELECT 
+            team_id,
+            assignee_id AS user_id,
+            COUNT(*) AS task_count
+        FROM tasks
+        WHERE assignee_id IS NOT NULL
+            AND created_at >= ?
+            AND created_at < ?
+            AND (? IS NULL OR team_id IN (SELECT team_id FROM team_members WHERE user_id = ?))
+            AND (? IS NULL OR team_id = ?)
+        GROUP BY team_id, assignee_id
+    ) AS user_counts
+) AS final_tab
+WHERE final_tab.rank_num <= ?
+`
+
+type GetTopAssigneesPerTeamParams struct {
+	FromDate time.Time
+	ToDate   time.Time
+	MemberID sql.NullInt64
+	TeamID   sql.NullInt64
+	TopN     int32
+}
+
+type GetTopAssigneesPerTeamRow struct {
+	TeamID    int64
+	UserID    sql.NullInt64
+	TaskCount int64
+	RankNum   interface{}
+}
+
+func (q *Queries) GetTopAssigneesPerTeam(ctx context.Context, arg GetTopAssigneesPerTeamParams) ([]GetTopAssigneesPerTeamRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTopAssigneesPerTeam,
+		arg.FromDate,
+		arg.ToDate,
+		arg.MemberID,
+		arg.MemberID,
+		arg.TeamID,
+		arg.TeamID,
+		arg.TopN,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTopAssigneesPerTeamRow
+	for rows.Next() {
+		var i GetTopAssigneesPerTeamRow
+		if err := rows.Scan(
+			&i.TeamID,
+			&i.UserID,
+			&i.TaskCount,
+			&i.RankNum,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTopCompletersPerTeam = `-- name: GetTopCompletersPerTeam :many
+SELECT 
+    final_tab.team_id,
+    final_tab.user_id,
+    final_tab.task_count,
+    final_tab.rank_num
+FROM (
+    SELECT 
+        user_counts.team_id,
+        user_counts.user_id,
+        user_counts.task_count,
+        DENSE_RANK() OVER (PARTITION BY user_counts.team_id ORDER BY user_counts.task_count DESC) as rank_num
+    FROM (
+        SELECT 
+            task.team_id,
+            th.changed_by AS user_id,
+            COUNT(DISTINCT th.task_id) AS task_count
+        FROM task_history th
+        JOIN tasks task ON th.task_id = task.id
+        WHERE th.change_type = 'status_update'
+            AND th.new_value = 'done'
+            AND th.changed_by IS NOT NULL
+            AND th.created_at >= ?
+            AND th.created_at < ?
+            AND (? IS NULL OR task.team_id IN (SELECT team_id FROM team_members WHERE user_id = ?))
+            AND (? IS NULL OR task.team_id = ?)
+        GROUP BY task.team_id, th.changed_by
+    ) AS user_counts
+) AS final_tab
+WHERE final_tab.rank_num <= ?
+`
+
+type GetTopCompletersPerTeamParams struct {
+	FromDate time.Time
+	ToDate   time.Time
+	MemberID sql.NullInt64
+	TeamID   sql.NullInt64
+	TopN     int32
+}
+
+type GetTopCompletersPerTeamRow struct {
+	TeamID    int64
+	UserID    sql.NullInt64
+	TaskCount int64
+	RankNum   interface{}
+}
+
+func (q *Queries) GetTopCompletersPerTeam(ctx context.Context, arg GetTopCompletersPerTeamParams) ([]GetTopCompletersPerTeamRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTopCompletersPerTeam,
+		arg.FromDate,
+		arg.ToDate,
+		arg.MemberID,
+		arg.MemberID,
+		arg.TeamID,
+		arg.TeamID,
+		arg.TopN,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTopCompletersPerTeamRow
+	for rows.Next() {
+		var i GetTopCompletersPerTeamRow
+		if err := rows.Scan(
+			&i.TeamID,
+			&i.UserID,
+			&i.TaskCount,
+			&i.RankNum,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -95,7 +295,7 @@ func (q *Queries) GetTeamStats(ctx context.Context) ([]GetTeamStatsRow, error) {
 	return items, nil
 }
 
-const getTopUsersPerTeam = `-- name: GetTopUsersPerTeam :many
+const getTopCreatorsPerTeam = `-- name: GetTopCreatorsPerTeam :many
 SELECT 
     final_tab.team_id,
     final_tab.user_id,
@@ -113,29 +313,48 @@ FROM (
             created_by AS user_id,
             COUNT(*) AS task_count
         FROM tasks
-        WHERE created_at >= NOW() - INTERVAL 1 MONTH
+        WHERE created_at >= ?
+            AND created_at < ?
+            AND (? IS NULL OR team_id IN (SELECT team_id FROM team_members WHERE user_id = ?))
+            AND (? IS NULL OR team_id = ?)
         GROUP BY team_id, created_by
     ) AS user_counts
 ) AS final_tab
-WHERE final_tab.rank_num <= 3
+WHERE final_tab.rank_num <= ?
 `
 
-type GetTopUsersPerTeamRow struct {
+type GetTopCreatorsPerTeamParams struct {
+	FromDate time.Time
+	ToDate   time.Time
+	MemberID sql.NullInt64
+	TeamID   sql.NullInt64
+	TopN     int32
+}
+
+type GetTopCreatorsPerTeamRow struct {
 	TeamID    int64
 	UserID    int64
 	TaskCount int64
 	RankNum   interface{}
 }
 
-func (q *Queries) GetTopUsersPerTeam(ctx context.Context) ([]GetTopUsersPerTeamRow, error) {
-	rows, err := q.db.QueryContext(ctx, getTopUsersPerTeam)
+func (q *Queries) GetTopCreatorsPerTeam(ctx context.Context, arg GetTopCreatorsPerTeamParams) ([]GetTopCreatorsPerTeamRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTopCreatorsPerTeam,
+		arg.FromDate,
+		arg.ToDate,
+		arg.MemberID,
+		arg.MemberID,
+		arg.TeamID,
+		arg.TeamID,
+		arg.TopN,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetTopUsersPerTeamRow
+	var items []GetTopCreatorsPerTeamRow
 	for rows.Next() {
-		var i GetTopUsersPerTeamRow
+		var i GetTopCreatorsPerTeamRow
 		if err := rows.Scan(
 			&i.TeamID,
 			&i.UserID,
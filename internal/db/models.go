@@ -8,49 +8,92 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
+	"time"
 )
 
-type TasksStatus string
+type TeamInvitationsRole string
 
 const (
-	TasksStatusTodo       TasksStatus = "todo"
-	TasksStatusInProgress TasksStatus = "in_progress"
-	TasksStatusDone       TasksStatus = "done"
+	TeamInvitationsRoleAdmin  TeamInvitationsRole = "admin"
+	TeamInvitationsRoleMember TeamInvitationsRole = "member"
 )
 
-func (e *TasksStatus) Scan(src interface{}) error {
+func (e *TeamInvitationsRole) Scan(src interface{}) error {
 	switch s := src.(type) {
 	case []byte:
-		*e = TasksStatus(s)
+		*e = TeamInvitationsRole(s)
 	case string:
-		*e = TasksStatus(s)
+		*e = TeamInvitationsRole(s)
 	default:
-		return fmt.Errorf("unsupported scan type for TasksStatus: %T", src)
+		return fmt.Errorf("unsupported scan type for TeamInvitationsRole: %T", src)
 	}
 	return nil
 }
 
-type NullTasksStatus struct {
-	TasksStatus TasksStatus
-	Valid       bool // Valid is true if TasksStatus is not NULL
+type NullTeamInvitationsRole struct {
+	TeamInvitationsRole TeamInvitationsRole
+	Valid               bool // Valid is true if TeamInvitationsRole is not NULL
 }
 
 // Scan implements the Scanner interface.
-func (ns *NullTasksStatus) Scan(value interface{}) error {
+func (ns *NullTeamInvitationsRole) Scan(value interface{}) error {
 	if value == nil {
-		ns.TasksStatus, ns.Valid = "", false
+		ns.TeamInvitationsRole, ns.Valid = "", false
 		return nil
 	}
 	ns.Valid = true
-	return ns.TasksStatus.Scan(value)
+	return ns.TeamInvitationsRole.Scan(value)
 }
 
 // Value implements the driver Valuer interface.
-func (ns NullTasksStatus) Value() (driver.Value, error) {
+func (ns NullTeamInvitationsRole) Value() (driver.Value, error) {
 	if !ns.Valid {
 		return nil, nil
 	}
-	return string(ns.TasksStatus), nil
+	return string(ns.TeamInvitationsRole), nil
+}
+
+type TeamInvitationsStatus string
+
+const (
+	TeamInvitationsStatusPending  TeamInvitationsStatus = "pending"
+	TeamInvitationsStatusAccepted TeamInvitationsStatus = "accepted"
+	TeamInvitationsStatusDeclined TeamInvitationsStatus = "declined"
+)
+
+func (e *TeamInvitationsStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = TeamInvitationsStatus(s)
+	case string:
+		*e = TeamInvitationsStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for TeamInvitationsStatus: %T", src)
+	}
+	return nil
+}
+
+type NullTeamInvitationsStatus struct {
+	TeamInvitationsStatus TeamInvitationsStatus
+	Valid                 bool // Valid is true if TeamInvitationsStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullTeamInvitationsStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.TeamInvitationsStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.TeamInvitationsStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullTeamInvitationsStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.TeamInvitationsStatus), nil
 }
 
 type TeamMembersRole string
@@ -96,16 +139,35 @@ func (ns NullTeamMembersRole) Value() (driver.Value, error) {
 	return string(ns.TeamMembersRole), nil
 }
 
+type PasswordResetToken struct {
+	ID        int64
+	UserID    int64
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt sql.NullTime
+}
+
+type RefreshToken struct {
+	ID        int64
+	UserID    int64
+	TokenHash string
+	ExpiresAt time.Time
+	RevokedAt sql.NullTime
+	CreatedAt sql.NullTime
+}
+
 type Task struct {
 	ID          int64
 	Title       string
 	Description sql.NullString
-	Status      TasksStatus
+	Status      string
 	TeamID      int64
 	AssigneeID  sql.NullInt64
 	CreatedBy   int64
 	CreatedAt   sql.NullTime
 	UpdatedAt   sql.NullTime
+	Position    int64
 }
 
 type TaskComment struct {
@@ -133,6 +195,19 @@ type Team struct {
 	CreatedAt sql.NullTime
 }
 
+type TeamInvitation struct {
+	ID          int64
+	TeamID      int64
+	Email       string
+	UserID      sql.NullInt64
+	Role        TeamInvitationsRole
+	Status      TeamInvitationsStatus
+	InvitedBy   int64
+	ExpiresAt   time.Time
+	CreatedAt   sql.NullTime
+	RespondedAt sql.NullTime
+}
+
 type TeamMember struct {
 	TeamID   int64
 	UserID   int64
@@ -140,9 +215,23 @@ type TeamMember struct {
 	JoinedAt sql.NullTime
 }
 
+type TeamStatus struct {
+	TeamID   int64
+	Name     string
+	Position int32
+}
+
+type TeamStatusTransition struct {
+	TeamID     int64
+	FromStatus string
+	ToStatus   string
+}
+
 type User struct {
 	ID           int64
 	Email        string
 	PasswordHash string
 	CreatedAt    sql.NullTime
+	IsAdmin      bool
+	DisplayName  sql.NullString
 }
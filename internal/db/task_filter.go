@@ -0,0 +1,232 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// This file is maintained by hand: task lists combine optional IN lists,
+// full-text search and a caller-chosen sort order, which sqlc cannot express
+// as a single static query.
+
+type TaskSortField string
+
+const (
+	TaskSortCreatedAt TaskSortField = "created_at"
+	TaskSortUpdatedAt TaskSortField = "updated_at"
+	TaskSortTitle     TaskSortField = "title"
+	TaskSortStatus    TaskSortField = "status"
+)
+
+// column returns the ORDER BY expression for the field. Statuses sort in the
+// default workflow order, with custom statuses alphabetically between
+// in_progress and done; see StatusSortKey.
+func (f TaskSortField) column() string {
+	if f == TaskSortStatus {
+		return "CONCAT(CASE status WHEN 'todo' THEN '0' WHEN 'in_progress' THEN '1' WHEN 'done' THEN '3' ELSE '2' END, status)"
+	}
+	return string(f)
+}
+
+// cursorValue converts a cursor value to what column yields for that task.
+func (f TaskSortField) cursorValue(value interface{}) interface{} {
+	if s, ok := value.(string); ok && f == TaskSortStatus {
+		return StatusSortKey(s)
+	}
+	return value
+}
+
+// StatusSortKey is the value status sorting compares: a rank of the status in
+// the default workflow followed by its name.
+func StatusSortKey(status string) string {
+	switch status {
+	case "todo":
+		return "0" + status
+	case "in_progress":
+		return "1" + status
+	case "done":
+		return "3" + status
+	}
+	return "2" + status
+}
+
+type TaskSort struct {
+	Field TaskSortField
+	Desc  bool
+}
+
+// TaskFilter narrows a task list. Zero values mean "no restriction"; TeamIDs
+// must not be empty.
+type TaskFilter struct {
+	TeamIDs     []int64
+	Statuses    []string
+	AssigneeID  sql.NullInt64
+	Unassigned  bool
+	CreatedBy   sql.NullInt64
+	CreatedFrom sql.NullTime
+	CreatedTo   sql.NullTime
+	UpdatedFrom sql.NullTime
+	UpdatedTo   sql.NullTime
+	// InvolvedUserID keeps tasks assigned to or created by the user.
+	InvolvedUserID sql.NullInt64
+	// SearchTerms must all appear (as word prefixes) in the title or description.
+	SearchTerms []string
+}
+
+// TaskCursor is the position after which the next page starts: the sort key
+// value of the last returned task and its id as a tie-breaker.
+type TaskCursor struct {
+	Value interface{}
+	ID    int64
+}
+
+type ListFilteredTasksParams struct {
+	Filter TaskFilter
+	Sort   TaskSort
+	After  *TaskCursor
+	Limit  int32
+}
+
+const taskColumns = "id, title, description, status, team_id, assignee_id, created_by, created_at, updated_at, position"
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func (f TaskFilter) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+
+	conds = append(conds, "team_id IN ("+placeholders(len(f.TeamIDs))+")")
+	for _, id := range f.TeamIDs {
+		args = append(args, id)
+	}
+
+	if len(f.Statuses) > 0 {
+		conds = append(conds, "status IN ("+placeholders(len(f.Statuses))+")")
+		for _, s := range f.Statuses {
+			args = append(args, s)
+		}
+	}
+	if f.Unassigned {
+		conds = append(conds, "assignee_id IS NULL")
+	} else if f.AssigneeID.Valid {
+		conds = append(conds, "assignee_id = ?")
+		args = append(args, f.AssigneeID)
+	}
+	if f.CreatedBy.Valid {
+		conds = append(conds, "created_by = ?")
+		args = append(args, f.CreatedBy)
+	}
+	if f.InvolvedUserID.Valid {
+		conds = append(conds, "(assignee_id = ? OR created_by = ?)")
+		args = append(args, f.InvolvedUserID, f.InvolvedUserID)
+	}
+
+	ranges := []struct {
+		cond  string
+		value sql.NullTime
+	}{
+		{"created_at >= ?", f.CreatedFrom},
+		{"created_at < ?", f.CreatedTo},
+		{"updated_at >= ?", f.UpdatedFrom},
+		{"updated_at < ?", f.UpdatedTo},
+	}
+	for _, r := range ranges {
+		if r.value.Valid {
+			conds = append(conds, r.cond)
+			args = append(args, r.value)
+		}
+	}
+
+	if len(f.SearchTerms) > 0 {
+		conds = append(conds, "MATCH(title, description) AGAINST (? IN BOOLEAN MODE)")
+		args = append(args, booleanSearchQuery(f.SearchTerms))
+	}
+
+	return strings.Join(conds, " AND "), args
+}
+
+// booleanSearchQuery requires every term and lets it match as a word prefix.
+// Terms are expected to be plain words: operator characters are dropped.
+func booleanSearchQuery(terms []string) string {
+	clean := func(r rune) rune {
+		if strings.ContainsRune(`+-<>()~*"@`, r) {
+			return -1
+		}
+		return r
+	}
+
+	parts := make([]string, 0, len(terms))
+	for _, t := range terms {
+		if t = strings.Map(clean, t); t != "" {
+			parts = append(parts, "+"+t+"*")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func (q *Queries) ListFilteredTasks(ctx context.Context, arg ListFilteredTasksParams) ([]Task, error) {
+	where, args := arg.Filter.where()
+
+	field := arg.Sort.Field
+	if field == "" {
+		field = TaskSortCreatedAt
+	}
+	column := field.column()
+	dir, cmp := "ASC", ">"
+	if arg.Sort.Desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	if arg.After != nil {
+		value := field.cursorValue(arg.After.Value)
+		where += " AND (" + column + " " + cmp + " ? OR (" + column + " = ? AND id " + cmp + " ?))"
+		args = append(args, value, value, arg.After.ID)
+	}
+
+	query := "SELECT " + taskColumns + " FROM tasks\nWHERE " + where +
+		"\nORDER BY " + column + " " + dir + ", id " + dir + "\nLIMIT ?"
+	args = append(args, arg.Limit)
+
+	rows, err := q.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.TeamID,
+			&i.AssigneeID,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Position,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (q *Queries) CountFilteredTasks(ctx context.Context, filter TaskFilter) (int64, error) {
+	where, args := filter.where()
+	row := q.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM tasks\nWHERE "+where, args...)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
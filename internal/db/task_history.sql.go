@@ -49,6 +49,46 @@ func (q *Queries) CreateTaskHistory(ctx context.Context, arg CreateTaskHistoryPa
 	return err
 }
 
+const deleteTaskComment = `-- name: DeleteTaskComment :exec
+DELETE FROM task_comments WHERE id = ?
+`
+
+func (q *Queries) DeleteTaskComment(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteTaskComment, id)
+	return err
+}
+
+const deleteTeamTaskComments = `-- name: DeleteTeamTaskComments :execrows
+DELETE FROM task_comments
+WHERE task_id IN (SELECT id FROM tasks WHERE team_id = ?)
+`
+
+func (q *Queries) DeleteTeamTaskComments(ctx context.Context, teamID int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteTeamTaskComments, teamID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getTaskCommentByID = `-- name: GetTaskCommentByID :one
+SELECT id, task_id, user_id, content, created_at FROM task_comments
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetTaskCommentByID(ctx context.Context, id int64) (TaskComment, error) {
+	row := q.db.QueryRowContext(ctx, getTaskCommentByID, id)
+	var i TaskComment
+	err := row.Scan(
+		&i.ID,
+		&i.TaskID,
+		&i.UserID,
+		&i.Content,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const listTaskComments = `-- name: ListTaskComments :many
 SELECT tc.id, tc.task_id, tc.user_id, tc.content, tc.created_at, u.email as user_email
 FROM task_comments tc
@@ -146,3 +186,19 @@ func (q *Queries) ListTaskHistory(ctx context.Context, taskID int64) ([]ListTask
 	}
 	return items, nil
 }
+
+const updateTaskComment = `-- name: UpdateTaskComment :exec
+UPDATE task_comments
+SET content = ?
+WHERE id = ?
+`
+
+type UpdateTaskCommentParams struct {
+	Content string
+	ID      int64
+}
+
+func (q *Queries) UpdateTaskComment(ctx context.Context, arg UpdateTaskCommentParams) error {
+	_, err := q.db.ExecContext(ctx, updateTaskComment, arg.Content, arg.ID)
+	return err
+}
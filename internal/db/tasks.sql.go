@@ -10,18 +10,53 @@ import (
 	"database/sql"
 )
 
+const countTeamTasksByStatus = `-- name: CountTeamTasksByStatus :many
+SELECT status, COUNT(*) AS total FROM tasks
+WHERE team_id = ?
+GROUP BY status
+`
+
+type CountTeamTasksByStatusRow struct {
+	Status string
+	Total  int64
+}
+
+func (q *Queries) CountTeamTasksByStatus(ctx context.Context, teamID int64) ([]CountTeamTasksByStatusRow, error) {
+	rows, err := q.db.QueryContext(ctx, countTeamTasksByStatus, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountTeamTasksByStatusRow
+	for rows.Next() {
+		var i CountTeamTasksByStatusRow
+		if err := rows.Scan(&i.Status, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createTask = `-- name: CreateTask :execresult
-INSERT INTO tasks (title, description, status, team_id, assignee_id, created_by) 
-VALUES (?, ?, ?, ?, ?, ?)
+INSERT INTO tasks (title, description, status, team_id, assignee_id, created_by, position) 
+VALUES (?, ?, ?, ?, ?, ?, ?)
 `
 
 type CreateTaskParams struct {
 	Title       string
 	Description sql.NullString
-	Status      TasksStatus
+	Status      string
 	TeamID      int64
 	AssigneeID  sql.NullInt64
 	CreatedBy   int64
+	Position    int64
 }
 
 func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (sql.Result, error) {
@@ -32,6 +67,7 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (sql.Res
 		arg.TeamID,
 		arg.AssigneeID,
 		arg.CreatedBy,
+		arg.Position,
 	)
 }
 
@@ -44,8 +80,20 @@ func (q *Queries) DeleteTask(ctx context.Context, id int64) error {
 	return err
 }
 
+const deleteTeamTasks = `-- name: DeleteTeamTasks :execrows
+DELETE FROM tasks WHERE team_id = ?
+`
+
+func (q *Queries) DeleteTeamTasks(ctx context.Context, teamID int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteTeamTasks, teamID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getTaskByID = `-- name: GetTaskByID :one
-SELECT id, title, description, status, team_id, assignee_id, created_by, created_at, updated_at FROM tasks 
+SELECT id, title, description, status, team_id, assignee_id, created_by, created_at, updated_at, position FROM tasks 
 WHERE id = ? LIMIT 1
 `
 
@@ -62,38 +110,152 @@ func (q *Queries) GetTaskByID(ctx context.Context, id int64) (Task, error) {
 		&i.CreatedBy,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Position,
+	)
+	return i, err
+}
+
+const getTaskByIDForUpdate = `-- name: GetTaskByIDForUpdate :one
+SELECT id, title, description, status, team_id, assignee_id, created_by, created_at, updated_at, position FROM tasks
+WHERE id = ? LIMIT 1
+FOR UPDATE
+`
+
+func (q *Queries) GetTaskByIDForUpdate(ctx context.Context, id int64) (Task, error) {
+	row := q.db.QueryRowContext(ctx, getTaskByIDForUpdate, id)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.TeamID,
+		&i.AssigneeID,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Position,
 	)
 	return i, err
 }
 
-const listTasks = `-- name: ListTasks :many
-SELECT id, title, description, status, team_id, assignee_id, created_by, created_at, updated_at FROM tasks
-WHERE 
-    team_id = ?
-    AND (? IS NULL OR status = ?)
-    AND (? IS NULL OR assignee_id = ?)
-ORDER BY created_at DESC
-LIMIT ? OFFSET ?
+const lastTaskPosition = `-- name: LastTaskPosition :one
+SELECT CAST(COALESCE(MAX(position), 0) AS SIGNED) AS last_position
+FROM tasks
+WHERE team_id = ? AND status = ?
 `
 
-type ListTasksParams struct {
+type LastTaskPositionParams struct {
+	TeamID int64
+	Status string
+}
+
+func (q *Queries) LastTaskPosition(ctx context.Context, arg LastTaskPositionParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, lastTaskPosition, arg.TeamID, arg.Status)
+	var last_position int64
+	err := row.Scan(&last_position)
+	return last_position, err
+}
+
+const listBoardColumn = `-- name: ListBoardColumn :many
+SELECT id, title, description, status, team_id, assignee_id, created_by, created_at, updated_at, position FROM tasks
+WHERE team_id = ? AND status = ?
+ORDER BY position, id
+LIMIT ?
+`
+
+type ListBoardColumnParams struct {
+	TeamID int64
+	Status string
+	Limit  int32
+}
+
+func (q *Queries) ListBoardColumn(ctx context.Context, arg ListBoardColumnParams) ([]Task, error) {
+	rows, err := q.db.QueryContext(ctx, listBoardColumn, arg.TeamID, arg.Status, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.TeamID,
+			&i.AssigneeID,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Position,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listColumnPositions = `-- name: ListColumnPositions :many
+SELECT id, position FROM tasks
+WHERE team_id = ? AND status = ?
+ORDER BY position, id
+`
+
+type ListColumnPositionsParams struct {
+	TeamID int64
+	Status string
+}
+
+type ListColumnPositionsRow struct {
+	ID       int64
+	Position int64
+}
+
+func (q *Queries) ListColumnPositions(ctx context.Context, arg ListColumnPositionsParams) ([]ListColumnPositionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listColumnPositions, arg.TeamID, arg.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListColumnPositionsRow
+	for rows.Next() {
+		var i ListColumnPositionsRow
+		if err := rows.Scan(&i.ID, &i.Position); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTeamTasksByAssignee = `-- name: ListTeamTasksByAssignee :many
+SELECT id, title, description, status, team_id, assignee_id, created_by, created_at, updated_at, position FROM tasks
+WHERE team_id = ? AND assignee_id = ?
+ORDER BY id
+`
+
+type ListTeamTasksByAssigneeParams struct {
 	TeamID     int64
-	Status     NullTasksStatus
 	AssigneeID sql.NullInt64
-	Limit      int32
-	Offset     int32
 }
 
-func (q *Queries) ListTasks(ctx context.Context, arg ListTasksParams) ([]Task, error) {
-	rows, err := q.db.QueryContext(ctx, listTasks,
-		arg.TeamID,
-		arg.Status,
-		arg.Status,
-		arg.AssigneeID,
-		arg.AssigneeID,
-		arg.Limit,
-		arg.Offset,
-	)
+func (q *Queries) ListTeamTasksByAssignee(ctx context.Context, arg ListTeamTasksByAssigneeParams) ([]Task, error) {
+	rows, err := q.db.QueryContext(ctx, listTeamTasksByAssignee, arg.TeamID, arg.AssigneeID)
 	if err != nil {
 		return nil, err
 	}
@@ -111,6 +273,7 @@ func (q *Queries) ListTasks(ctx context.Context, arg ListTasksParams) ([]Task, e
 			&i.CreatedBy,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Position,
 		); err != nil {
 			return nil, err
 		}
@@ -125,6 +288,72 @@ func (q *Queries) ListTasks(ctx context.Context, arg ListTasksParams) ([]Task, e
 	return items, nil
 }
 
+const moveTask = `-- name: MoveTask :exec
+UPDATE tasks
+SET status = ?, position = ?
+WHERE id = ?
+`
+
+type MoveTaskParams struct {
+	Status   string
+	Position int64
+	ID       int64
+}
+
+func (q *Queries) MoveTask(ctx context.Context, arg MoveTaskParams) error {
+	_, err := q.db.ExecContext(ctx, moveTask, arg.Status, arg.Position, arg.ID)
+	return err
+}
+
+const patchTask = `-- name: PatchTask :exec
+UPDATE tasks
+SET
+    title = COALESCE(?, title),
+    description = IF(?, ?, description),
+    status = COALESCE(?, status),
+    assignee_id = IF(?, ?, assignee_id)
+WHERE id = ?
+`
+
+type PatchTaskParams struct {
+	Title          sql.NullString
+	SetDescription bool
+	Description    sql.NullString
+	Status         sql.NullString
+	SetAssignee    bool
+	AssigneeID     sql.NullInt64
+	ID             int64
+}
+
+func (q *Queries) PatchTask(ctx context.Context, arg PatchTaskParams) error {
+	_, err := q.db.ExecContext(ctx, patchTask,
+		arg.Title,
+		arg.SetDescription,
+		arg.Description,
+		arg.Status,
+		arg.SetAssignee,
+		arg.AssigneeID,
+		arg.ID,
+	)
+	return err
+}
+
+const setTaskPosition = `-- name: SetTaskPosition :exec
+UPDATE tasks
+SET position = ?, updated_at = updated_at
+WHERE id = ?
+`
+
+type SetTaskPositionParams struct {
+	Position int64
+	ID       int64
+}
+
+func (q *Queries) SetTaskPosition(ctx context.Context, arg SetTaskPositionParams) error {
+	_, err := q.db.ExecContext(ctx, setTaskPosition, arg.Position, arg.ID)
+	return err
+}
+
 const updateTask = `-- name: UpdateTask :exec
 UPDATE tasks 
 SET title = ?, description = ?, status = ?, assignee_id = ? 
@@ -134,7 +363,7 @@ WHERE id = ?
 type UpdateTaskParams struct {
 	Title       string
 	Description sql.NullString
-	Status      TasksStatus
+	Status      string
 	AssigneeID  sql.NullInt64
 	ID          int64
 }
@@ -26,6 +26,19 @@ func (q *Queries) AddTeamMember(ctx context.Context, arg AddTeamMemberParams) er
 	return err
 }
 
+const countTeamOwners = `-- name: CountTeamOwners :one
+SELECT COUNT(*) FROM team_members
+WHERE team_id = ? AND role = 'owner'
+FOR UPDATE
+`
+
+func (q *Queries) CountTeamOwners(ctx context.Context, teamID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countTeamOwners, teamID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createTeam = `-- name: CreateTeam :execresult
 INSERT INTO teams (name, created_by) 
 VALUES (?, ?)
@@ -40,6 +53,27 @@ func (q *Queries) CreateTeam(ctx context.Context, arg CreateTeamParams) (sql.Res
 	return q.db.ExecContext(ctx, createTeam, arg.Name, arg.CreatedBy)
 }
 
+const deleteTeam = `-- name: DeleteTeam :exec
+DELETE FROM teams WHERE id = ?
+`
+
+func (q *Queries) DeleteTeam(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteTeam, id)
+	return err
+}
+
+const deleteTeamMembers = `-- name: DeleteTeamMembers :execrows
+DELETE FROM team_members WHERE team_id = ?
+`
+
+func (q *Queries) DeleteTeamMembers(ctx context.Context, teamID int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteTeamMembers, teamID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getTeamByID = `-- name: GetTeamByID :one
 SELECT id, name, created_by, created_at FROM teams 
 WHERE id = ? LIMIT 1
@@ -74,6 +108,49 @@ func (q *Queries) GetUserRoleInTeam(ctx context.Context, arg GetUserRoleInTeamPa
 	return role, err
 }
 
+const listTeamMembers = `-- name: ListTeamMembers :many
+SELECT tm.user_id, u.email, tm.role, tm.joined_at
+FROM team_members tm
+JOIN users u ON u.id = tm.user_id
+WHERE tm.team_id = ?
+ORDER BY tm.joined_at, tm.user_id
+`
+
+type ListTeamMembersRow struct {
+	UserID   int64
+	Email    string
+	Role     TeamMembersRole
+	JoinedAt sql.NullTime
+}
+
+func (q *Queries) ListTeamMembers(ctx context.Context, teamID int64) ([]ListTeamMembersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTeamMembers, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTeamMembersRow
+	for rows.Next() {
+		var i ListTeamMembersRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Email,
+			&i.Role,
+			&i.JoinedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listUserTeams = `-- name: ListUserTeams :many
 SELECT t.id, t.name, t.created_by, t.created_at, tm.role 
 FROM teams t
@@ -117,3 +194,63 @@ func (q *Queries) ListUserTeams(ctx context.Context, userID int64) ([]ListUserTe
 	}
 	return items, nil
 }
+
+const lockTeam = `-- name: LockTeam :one
+SELECT id FROM teams
+WHERE id = ?
+FOR UPDATE
+`
+
+func (q *Queries) LockTeam(ctx context.Context, id int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, lockTeam, id)
+	err := row.Scan(&id)
+	return id, err
+}
+
+const removeTeamMember = `-- name: RemoveTeamMember :exec
+DELETE FROM team_members
+WHERE team_id = ? AND user_id = ?
+`
+
+type RemoveTeamMemberParams struct {
+	TeamID int64
+	UserID int64
+}
+
+func (q *Queries) RemoveTeamMember(ctx context.Context, arg RemoveTeamMemberParams) error {
+	_, err := q.db.ExecContext(ctx, removeTeamMember, arg.TeamID, arg.UserID)
+	return err
+}
+
+const updateTeamMemberRole = `-- name: UpdateTeamMemberRole :exec
+UPDATE team_members
+SET role = ?
+WHERE team_id = ? AND user_id = ?
+`
+
+type UpdateTeamMemberRoleParams struct {
+	Role   TeamMembersRole
+	TeamID int64
+	UserID int64
+}
+
+func (q *Queries) UpdateTeamMemberRole(ctx context.Context, arg UpdateTeamMemberRoleParams) error {
+	_, err := q.db.ExecContext(ctx, updateTeamMemberRole, arg.Role, arg.TeamID, arg.UserID)
+	return err
+}
+
+const updateTeamName = `-- name: UpdateTeamName :exec
+UPDATE teams
+SET name = ?
+WHERE id = ?
+`
+
+type UpdateTeamNameParams struct {
+	Name string
+	ID   int64
+}
+
+func (q *Queries) UpdateTeamName(ctx context.Context, arg UpdateTeamNameParams) error {
+	_, err := q.db.ExecContext(ctx, updateTeamName, arg.Name, arg.ID)
+	return err
+}
@@ -25,7 +25,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (sql.Res
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, password_hash, created_at FROM users 
+SELECT id, email, password_hash, created_at, is_admin, display_name FROM users 
 WHERE email = ? LIMIT 1
 `
 
@@ -37,12 +37,14 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.PasswordHash,
 		&i.CreatedAt,
+		&i.IsAdmin,
+		&i.DisplayName,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, email, password_hash, created_at FROM users 
+SELECT id, email, password_hash, created_at, is_admin, display_name FROM users 
 WHERE id = ? LIMIT 1
 `
 
@@ -54,6 +56,60 @@ func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
 		&i.Email,
 		&i.PasswordHash,
 		&i.CreatedAt,
+		&i.IsAdmin,
+		&i.DisplayName,
 	)
 	return i, err
 }
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET password_hash = ?
+WHERE id = ?
+`
+
+type UpdateUserPasswordParams struct {
+	PasswordHash string
+	ID           int64
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.PasswordHash, arg.ID)
+	return err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :exec
+UPDATE users
+SET display_name = ?
+WHERE id = ?
+`
+
+type UpdateUserProfileParams struct {
+	DisplayName sql.NullString
+	ID          int64
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserProfile, arg.DisplayName, arg.ID)
+	return err
+}
+
+const usersShareTeam = `-- name: UsersShareTeam :one
+SELECT EXISTS(
+    SELECT 1 FROM team_members a
+    JOIN team_members b ON a.team_id = b.team_id
+    WHERE a.user_id = ? AND b.user_id = ?
+) AS shared
+`
+
+type UsersShareTeamParams struct {
+	UserID   int64
+	UserID_2 int64
+}
+
+func (q *Queries) UsersShareTeam(ctx context.Context, arg UsersShareTeamParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, usersShareTeam, arg.UserID, arg.UserID_2)
+	var shared bool
+	err := row.Scan(&shared)
+	return shared, err
+}
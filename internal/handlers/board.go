@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+	json_resp "github.com/egor_lukyanovich/moon_test_application/pkg/json"
+	id_helper "github.com/egor_lukyanovich/moon_test_application/pkg/routing"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/validation"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// taskPositionStep is the gap between neighbouring tasks of a column, so a
+	// task can usually be moved by updating only its own position.
+	taskPositionStep = 1024
+
+	defaultBoardLimit = 50
+	maxBoardLimit     = 200
+)
+
+// placeInColumn finds the position for a task inserted at index of column,
+// which is ordered and does not contain the task itself. When the neighbours
+// leave no room the column is renumbered; the tasks whose position changes are
+// returned with their new positions.
+func placeInColumn(column []db.ListColumnPositionsRow, index int) (int64, []db.ListColumnPositionsRow) {
+	if index > len(column) {
+		index = len(column)
+	}
+
+	switch {
+	case len(column) == 0:
+		return taskPositionStep, nil
+	case index == 0:
+		return column[0].Position - taskPositionStep, nil
+	case index == len(column):
+		return column[index-1].Position + taskPositionStep, nil
+	}
+
+	prev, next := column[index-1].Position, column[index].Position
+	if next-prev >= 2 {
+		return prev + (next-prev)/2, nil
+	}
+
+	var position int64
+	var renumbered []db.ListColumnPositionsRow
+	next = taskPositionStep
+	for i, row := range column {
+		if i == index {
+			position = next
+			next += taskPositionStep
+		}
+		if row.Position != next {
+			renumbered = append(renumbered, db.ListColumnPositionsRow{ID: row.ID, Position: next})
+		}
+		next += taskPositionStep
+	}
+	return position, renumbered
+}
+
+// columnIndex returns where taskID is in column and the column without it.
+func columnIndex(column []db.ListColumnPositionsRow, taskID int64) (int, []db.ListColumnPositionsRow) {
+	for i, row := range column {
+		if row.ID == taskID {
+			rest := append(column[:i:i], column[i+1:]...)
+			return i, rest
+		}
+	}
+	return -1, column
+}
+
+// appendToColumn puts a task that changed status at the bottom of its new
+// column.
+func appendToColumn(ctx context.Context, qtx *db.Queries, task db.Task) error {
+	last, err := qtx.LastTaskPosition(ctx, db.LastTaskPositionParams{TeamID: task.TeamID, Status: task.Status})
+	if err != nil {
+		return err
+	}
+	return qtx.SetTaskPosition(ctx, db.SetTaskPositionParams{Position: last + taskPositionStep, ID: task.ID})
+}
+
+// lockTask starts a write of a task inside tx. The task's team is locked
+// before anything else is read in tx, because MySQL fixes a transaction's
+// snapshot at its first plain read: the board and the workflow read afterwards
+// then stay current until commit, and writes that touch positions cannot
+// interleave. The team id is therefore looked up outside tx. On failure the
+// response is written and false returned.
+func (h *TaskHandlers) lockTask(w http.ResponseWriter, r *http.Request, qtx *db.Queries, taskID int64) (db.Task, bool) {
+	task, err := h.q.GetTaskByID(r.Context(), taskID)
+	if err != nil {
+		json_resp.RespondError(w, 404, "NOT_FOUND", "task not found")
+		return db.Task{}, false
+	}
+
+	if _, err := qtx.LockTeam(r.Context(), task.TeamID); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to lock team")
+		return db.Task{}, false
+	}
+
+	task, err = qtx.GetTaskByIDForUpdate(r.Context(), taskID)
+	if err != nil {
+		json_resp.RespondError(w, 404, "NOT_FOUND", "task not found")
+		return db.Task{}, false
+	}
+	return task, true
+}
+
+type boardColumn struct {
+	Status string    `json:"status"`
+	Total  int64     `json:"total"`
+	Tasks  []db.Task `json:"tasks"`
+}
+
+// Board returns the team's tasks grouped by status in workflow order, each
+// column sorted by position. limit caps the tasks per column; total tells how
+// many there are.
+func (h *TaskHandlers) Board(w http.ResponseWriter, r *http.Request) {
+	userID, ok := id_helper.GetUserIDHelper(r.Context())
+	if !ok {
+		json_resp.RespondError(w, 401, "UNAUTHORIZED", "unauthorized")
+		return
+	}
+
+	teamID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		json_resp.RespondError(w, 400, "BAD_REQUEST", "invalid team id")
+		return
+	}
+
+	limit := defaultBoardLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		v := validation.New()
+		limit, err = strconv.Atoi(raw)
+		v.Check(err == nil && limit >= 1 && limit <= maxBoardLimit, "limit",
+			"must be an integer between 1 and "+strconv.Itoa(maxBoardLimit))
+		if !v.Valid() {
+			json_resp.RespondValidationError(w, v.Errors())
+			return
+		}
+	}
+
+	if !id_helper.CheckTeamRole(r.Context(), h.q, teamID, userID) {
+		json_resp.RespondError(w, 403, "FORBIDDEN", "you are not a member of this team")
+		return
+	}
+
+	wf, err := loadWorkflow(r.Context(), h.q, teamID)
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to fetch workflow")
+		return
+	}
+
+	counts, err := h.q.CountTeamTasksByStatus(r.Context(), teamID)
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to count tasks")
+		return
+	}
+	totals := map[string]int64{}
+	for _, c := range counts {
+		totals[c.Status] = c.Total
+	}
+
+	columns := make([]boardColumn, 0, len(wf.Statuses))
+	for _, status := range wf.Statuses {
+		tasks, err := h.q.ListBoardColumn(r.Context(), db.ListBoardColumnParams{
+			TeamID: teamID,
+			Status: status,
+			Limit:  int32(limit),
+		})
+		if err != nil {
+			json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to fetch tasks")
+			return
+		}
+		if tasks == nil {
+			tasks = []db.Task{}
+		}
+		columns = append(columns, boardColumn{Status: status, Total: totals[status], Tasks: tasks})
+	}
+
+	json_resp.RespondJSON(w, 200, map[string]interface{}{
+		"team_id": teamID,
+		"columns": columns,
+	})
+}
+
+// MoveTask puts a task at a 0-based position of a column, changing its status
+// when the column differs and the team's workflow allows it. Every task write
+// that touches positions locks the team row first, so concurrent writes cannot
+// interleave with the renumbering.
+func (h *TaskHandlers) MoveTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := id_helper.GetUserIDHelper(r.Context())
+	if !ok {
+		json_resp.RespondError(w, 401, "UNAUTHORIZED", "unauthorized")
+		return
+	}
+
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		json_resp.RespondError(w, 400, "BAD_REQUEST", "invalid task id")
+		return
+	}
+
+	var req struct {
+		Status   *string `json:"status"`
+		Position *int    `json:"position"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json_resp.RespondError(w, 400, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	v := validation.New()
+	v.Check(req.Position != nil, "position", "is required")
+	if req.Position != nil {
+		v.Check(*req.Position >= 0, "position", "must not be negative")
+	}
+	if req.Status != nil {
+		validateStatus(v, *req.Status)
+	}
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "tx failed")
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.q.WithTx(tx)
+
+	oldTask, ok := h.lockTask(w, r, qtx, taskID)
+	if !ok {
+		return
+	}
+
+	if !id_helper.CheckTeamRole(r.Context(), qtx, oldTask.TeamID, userID) {
+		json_resp.RespondError(w, 403, "FORBIDDEN", "access denied")
+		return
+	}
+
+	newTask := oldTask
+	if req.Status != nil {
+		newTask.Status = *req.Status
+	}
+	if !h.checkStatusChange(w, r, v, qtx, oldTask, newTask) {
+		return
+	}
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
+	column, err := qtx.ListColumnPositions(r.Context(), db.ListColumnPositionsParams{TeamID: newTask.TeamID, Status: newTask.Status})
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to fetch column")
+		return
+	}
+	oldIndex, column := columnIndex(column, taskID)
+	if newTask.Status != oldTask.Status {
+		oldColumn, err := qtx.ListColumnPositions(r.Context(), db.ListColumnPositionsParams{TeamID: oldTask.TeamID, Status: oldTask.Status})
+		if err != nil {
+			json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to fetch column")
+			return
+		}
+		oldIndex, _ = columnIndex(oldColumn, taskID)
+	}
+
+	newIndex := min(*req.Position, len(column))
+	moved := map[string]interface{}{"task_id": taskID, "column": newTask.Status, "position": newIndex}
+
+	if newTask.Status == oldTask.Status && newIndex == oldIndex {
+		json_resp.RespondJSON(w, 200, moved)
+		return
+	}
+
+	position, renumbered := placeInColumn(column, newIndex)
+	for _, row := range renumbered {
+		if err := qtx.SetTaskPosition(r.Context(), db.SetTaskPositionParams{Position: row.Position, ID: row.ID}); err != nil {
+			json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to reorder column")
+			return
+		}
+	}
+
+	err = qtx.MoveTask(r.Context(), db.MoveTaskParams{Status: newTask.Status, Position: position, ID: taskID})
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to move task")
+		return
+	}
+	newTask.Position = position
+
+	if err := recordTaskChanges(r.Context(), qtx, userID, oldTask, newTask); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to write task history")
+		return
+	}
+	err = qtx.CreateTaskHistory(r.Context(), db.CreateTaskHistoryParams{
+		TaskID:     taskID,
+		ChangedBy:  sql.NullInt64{Int64: userID, Valid: true},
+		ChangeType: "moved",
+		OldValue:   sql.NullString{String: fmt.Sprintf("%s:%d", oldTask.Status, oldIndex), Valid: true},
+		NewValue:   sql.NullString{String: fmt.Sprintf("%s:%d", newTask.Status, newIndex), Valid: true},
+	})
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to write task history")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to commit tx")
+		return
+	}
+
+	h.cache.Invalidate(r.Context(), oldTask.TeamID)
+
+	json_resp.RespondJSON(w, 200, moved)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+)
+
+func TestPlaceInColumn(t *testing.T) {
+	column := func(positions ...int64) []db.ListColumnPositionsRow {
+		rows := make([]db.ListColumnPositionsRow, len(positions))
+		for i, p := range positions {
+			rows[i] = db.ListColumnPositionsRow{ID: int64(i + 1), Position: p}
+		}
+		return rows
+	}
+
+	cases := []struct {
+		name       string
+		column     []db.ListColumnPositionsRow
+		index      int
+		position   int64
+		renumbered int
+	}{
+		{"empty column", nil, 0, 1024, 0},
+		{"top", column(1024, 2048), 0, 0, 0},
+		{"bottom", column(1024, 2048), 2, 3072, 0},
+		{"index past the end", column(1024), 7, 2048, 0},
+		{"between", column(1024, 2048), 1, 1536, 0},
+		{"no gap left", column(1024, 1025, 1026), 1, 2048, 2},
+		{"equal positions", column(5, 5), 1, 2048, 2},
+	}
+
+	for _, tc := range cases {
+		position, renumbered := placeInColumn(tc.column, tc.index)
+		if position != tc.position || len(renumbered) != tc.renumbered {
+			t.Errorf("%s: expected position %d with %d renumbered, got %d with %v",
+				tc.name, tc.position, tc.renumbered, position, renumbered)
+		}
+	}
+
+	// After renumbering the column keeps its order around the new slot.
+	_, renumbered := placeInColumn(column(1024, 1025, 1026), 1)
+	if renumbered[0] != (db.ListColumnPositionsRow{ID: 2, Position: 3072}) ||
+		renumbered[1] != (db.ListColumnPositionsRow{ID: 3, Position: 4096}) {
+		t.Errorf("unexpected renumbering: %v", renumbered)
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	column := []db.ListColumnPositionsRow{{ID: 1}, {ID: 2}, {ID: 3}}
+
+	index, rest := columnIndex(column, 2)
+	if index != 1 || len(rest) != 2 || rest[0].ID != 1 || rest[1].ID != 3 {
+		t.Errorf("expected task 2 at index 1, got %d %v", index, rest)
+	}
+	if column[1].ID != 2 {
+		t.Errorf("expected the original column to be left intact, got %v", column)
+	}
+
+	if index, rest := columnIndex(column, 9); index != -1 || len(rest) != 3 {
+		t.Errorf("expected a missing task to leave the column as is, got %d %v", index, rest)
+	}
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+	json_resp "github.com/egor_lukyanovich/moon_test_application/pkg/json"
+	id_helper "github.com/egor_lukyanovich/moon_test_application/pkg/routing"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/validation"
+	"github.com/go-chi/chi/v5"
+)
+
+type CommentHandlers struct {
+	q  *db.Queries
+	db *sql.DB
+}
+
+func NewCommentHandlers(q *db.Queries, database *sql.DB) *CommentHandlers {
+	return &CommentHandlers{q: q, db: database}
+}
+
+type commentRequest struct {
+	Content string `json:"content"`
+}
+
+func (h *CommentHandlers) CreateComment(w http.ResponseWriter, r *http.Request) {
+	userID, task, ok := h.authorizeTask(w, r)
+	if !ok {
+		return
+	}
+
+	var req commentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json_resp.RespondError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	v := validation.New()
+	v.Required("content", req.Content)
+	v.MaxLength("content", req.Content, maxCommentLength)
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to start tx")
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.q.WithTx(tx)
+
+	res, err := qtx.CreateTaskComment(r.Context(), db.CreateTaskCommentParams{
+		TaskID:  task.ID,
+		UserID:  userID,
+		Content: req.Content,
+	})
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to create comment")
+		return
+	}
+
+	commentID, _ := res.LastInsertId()
+
+	err = qtx.CreateTaskHistory(r.Context(), db.CreateTaskHistoryParams{
+		TaskID:     task.ID,
+		ChangedBy:  sql.NullInt64{Int64: userID, Valid: true},
+		ChangeType: "comment_added",
+		NewValue:   sql.NullString{String: req.Content, Valid: true},
+	})
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to write task history")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to commit tx")
+		return
+	}
+
+	json_resp.RespondJSON(w, http.StatusCreated, map[string]interface{}{"comment_id": commentID})
+}
+
+func (h *CommentHandlers) ListComments(w http.ResponseWriter, r *http.Request) {
+	_, task, ok := h.authorizeTask(w, r)
+	if !ok {
+		return
+	}
+
+	comments, err := h.q.ListTaskComments(r.Context(), task.ID)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to fetch comments")
+		return
+	}
+
+	if comments == nil {
+		comments = []db.ListTaskCommentsRow{}
+	}
+
+	json_resp.RespondJSON(w, http.StatusOK, comments)
+}
+
+func (h *CommentHandlers) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	userID, task, ok := h.authorizeTask(w, r)
+	if !ok {
+		return
+	}
+
+	comment, ok := h.ownComment(w, r, task.ID, userID)
+	if !ok {
+		return
+	}
+
+	var req commentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json_resp.RespondError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	v := validation.New()
+	v.Required("content", req.Content)
+	v.MaxLength("content", req.Content, maxCommentLength)
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
+	err := h.q.UpdateTaskComment(r.Context(), db.UpdateTaskCommentParams{
+		Content: req.Content,
+		ID:      comment.ID,
+	})
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update comment")
+		return
+	}
+
+	json_resp.RespondJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+}
+
+func (h *CommentHandlers) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	userID, task, ok := h.authorizeTask(w, r)
+	if !ok {
+		return
+	}
+
+	comment, ok := h.ownComment(w, r, task.ID, userID)
+	if !ok {
+		return
+	}
+
+	if err := h.q.DeleteTaskComment(r.Context(), comment.ID); err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to delete comment")
+		return
+	}
+
+	json_resp.RespondJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func (h *CommentHandlers) authorizeTask(w http.ResponseWriter, r *http.Request) (int64, db.Task, bool) {
+	userID, ok := id_helper.GetUserIDHelper(r.Context())
+	if !ok {
+		json_resp.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+		return 0, db.Task{}, false
+	}
+
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid task id")
+		return 0, db.Task{}, false
+	}
+
+	task, err := h.q.GetTaskByID(r.Context(), taskID)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusNotFound, "NOT_FOUND", "task not found")
+		return 0, db.Task{}, false
+	}
+
+	if !id_helper.CheckTeamRole(r.Context(), h.q, task.TeamID, userID) {
+		json_resp.RespondError(w, http.StatusForbidden, "FORBIDDEN", "you don't have access to this task's comments")
+		return 0, db.Task{}, false
+	}
+
+	return userID, task, true
+}
+
+func (h *CommentHandlers) ownComment(w http.ResponseWriter, r *http.Request, taskID, userID int64) (db.TaskComment, bool) {
+	commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid comment id")
+		return db.TaskComment{}, false
+	}
+
+	comment, err := h.q.GetTaskCommentByID(r.Context(), commentID)
+	if err != nil || comment.TaskID != taskID {
+		json_resp.RespondError(w, http.StatusNotFound, "NOT_FOUND", "comment not found")
+		return db.TaskComment{}, false
+	}
+
+	if comment.UserID != userID {
+		json_resp.RespondError(w, http.StatusForbidden, "FORBIDDEN", "you can only modify your own comments")
+		return db.TaskComment{}, false
+	}
+
+	return comment, true
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+	id_helper "github.com/egor_lukyanovich/moon_test_application/pkg/routing"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestTaskComments(t *testing.T) {
+	database, cleanupDB := setupTestDBWithTasks(t)
+	defer cleanupDB()
+
+	queries := db.New(database)
+	commentHandlers := NewCommentHandlers(queries, database)
+
+	resAuthor, _ := queries.CreateUser(context.Background(), db.CreateUserParams{
+		Email: "author@example.com", PasswordHash: "hash",
+	})
+	authorID, _ := resAuthor.LastInsertId()
+
+	resOther, _ := queries.CreateUser(context.Background(), db.CreateUserParams{
+		Email: "other@example.com", PasswordHash: "hash",
+	})
+	otherID, _ := resOther.LastInsertId()
+
+	resOutsider, _ := queries.CreateUser(context.Background(), db.CreateUserParams{
+		Email: "outsider@example.com", PasswordHash: "hash",
+	})
+	outsiderID, _ := resOutsider.LastInsertId()
+
+	resTeam, _ := queries.CreateTeam(context.Background(), db.CreateTeamParams{
+		Name: "Comment Team", CreatedBy: authorID,
+	})
+	teamID, _ := resTeam.LastInsertId()
+
+	_ = queries.AddTeamMember(context.Background(), db.AddTeamMemberParams{
+		TeamID: teamID, UserID: authorID, Role: "owner",
+	})
+	_ = queries.AddTeamMember(context.Background(), db.AddTeamMemberParams{
+		TeamID: teamID, UserID: otherID, Role: "member",
+	})
+
+	resTask, _ := queries.CreateTask(context.Background(), db.CreateTaskParams{
+		Title: "Discuss me", Status: "todo", TeamID: teamID, CreatedBy: authorID,
+	})
+	taskID, _ := resTask.LastInsertId()
+
+	r := chi.NewRouter()
+	r.Post("/tasks/{id}/comments", commentHandlers.CreateComment)
+	r.Get("/tasks/{id}/comments", commentHandlers.ListComments)
+	r.Put("/tasks/{id}/comments/{commentID}", commentHandlers.UpdateComment)
+	r.Delete("/tasks/{id}/comments/{commentID}", commentHandlers.DeleteComment)
+
+	commentsURL := "/tasks/" + strconv.FormatInt(taskID, 10) + "/comments"
+
+	do := func(method, url string, body []byte, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req = req.WithContext(id_helper.WithUserID(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rrCreate := do(http.MethodPost, commentsURL, []byte(`{"content": "first!"}`), authorID)
+	if rrCreate.Code != http.StatusCreated {
+		t.Fatalf("expected status %v; got %v. Body: %s", http.StatusCreated, rrCreate.Code, rrCreate.Body.String())
+	}
+
+	var created map[string]interface{}
+	json.NewDecoder(rrCreate.Body).Decode(&created)
+	commentURL := commentsURL + "/" + strconv.FormatInt(int64(created["comment_id"].(float64)), 10)
+
+	var count int
+	err := database.QueryRow("SELECT COUNT(*) FROM task_history WHERE task_id = ? AND change_type = 'comment_added'", taskID).Scan(&count)
+	if err != nil || count != 1 {
+		t.Errorf("expected comment to be recorded in task history, count: %v", count)
+	}
+
+	rrOutsider := do(http.MethodGet, commentsURL, nil, outsiderID)
+	if rrOutsider.Code != http.StatusForbidden {
+		t.Errorf("expected status %v for non-member; got %v", http.StatusForbidden, rrOutsider.Code)
+	}
+
+	rrList := do(http.MethodGet, commentsURL, nil, otherID)
+	if rrList.Code != http.StatusOK {
+		t.Errorf("expected status %v; got %v", http.StatusOK, rrList.Code)
+	}
+
+	var comments []map[string]interface{}
+	json.NewDecoder(rrList.Body).Decode(&comments)
+	if len(comments) != 1 {
+		t.Errorf("expected 1 comment, got %d", len(comments))
+	}
+
+	rrForeignEdit := do(http.MethodPut, commentURL, []byte(`{"content": "hijacked"}`), otherID)
+	if rrForeignEdit.Code != http.StatusForbidden {
+		t.Errorf("expected status %v when editing someone else's comment; got %v", http.StatusForbidden, rrForeignEdit.Code)
+	}
+
+	rrEdit := do(http.MethodPut, commentURL, []byte(`{"content": "edited"}`), authorID)
+	if rrEdit.Code != http.StatusOK {
+		t.Errorf("expected status %v; got %v", http.StatusOK, rrEdit.Code)
+	}
+
+	rrDelete := do(http.MethodDelete, commentURL, nil, authorID)
+	if rrDelete.Code != http.StatusOK {
+		t.Errorf("expected status %v; got %v", http.StatusOK, rrDelete.Code)
+	}
+
+	err = database.QueryRow("SELECT COUNT(*) FROM task_comments WHERE task_id = ?", taskID).Scan(&count)
+	if err != nil || count != 0 {
+		t.Errorf("expected comment to be deleted, count: %v", count)
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+	json_resp "github.com/egor_lukyanovich/moon_test_application/pkg/json"
+	id_helper "github.com/egor_lukyanovich/moon_test_application/pkg/routing"
+	"github.com/go-chi/chi/v5"
+)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+func (h *TeamHandlers) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := id_helper.GetUserIDHelper(r.Context())
+	if !ok {
+		json_resp.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+		return
+	}
+
+	invitations, err := h.q.ListUserPendingInvitations(r.Context(), sql.NullInt64{Int64: userID, Valid: true})
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to fetch invitations")
+		return
+	}
+	if invitations == nil {
+		invitations = []db.ListUserPendingInvitationsRow{}
+	}
+
+	json_resp.RespondJSON(w, http.StatusOK, invitations)
+}
+
+func (h *TeamHandlers) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	userID, invitation, ok := h.pendingInvitation(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to start tx")
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.q.WithTx(tx)
+
+	if !h.respondToInvitation(w, r, qtx, invitation.ID, db.TeamInvitationsStatusAccepted) {
+		return
+	}
+
+	err = qtx.AddTeamMember(r.Context(), db.AddTeamMemberParams{
+		TeamID: invitation.TeamID,
+		UserID: userID,
+		Role:   db.TeamMembersRole(invitation.Role),
+	})
+	if err != nil {
+		json_resp.RespondError(w, http.StatusConflict, "CONFLICT", "you are already a member of this team")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to commit tx")
+		return
+	}
+
+	json_resp.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  db.TeamInvitationsStatusAccepted,
+		"team_id": invitation.TeamID,
+		"role":    invitation.Role,
+	})
+}
+
+func (h *TeamHandlers) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	_, invitation, ok := h.pendingInvitation(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.respondToInvitation(w, r, h.q, invitation.ID, db.TeamInvitationsStatusDeclined) {
+		return
+	}
+
+	json_resp.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  db.TeamInvitationsStatusDeclined,
+		"team_id": invitation.TeamID,
+	})
+}
+
+// pendingInvitation loads an invitation addressed to the caller. Invitations
+// of other users are reported as missing rather than forbidden.
+func (h *TeamHandlers) pendingInvitation(w http.ResponseWriter, r *http.Request) (int64, db.TeamInvitation, bool) {
+	userID, ok := id_helper.GetUserIDHelper(r.Context())
+	if !ok {
+		json_resp.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+		return 0, db.TeamInvitation{}, false
+	}
+
+	invitationID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid invitation id")
+		return 0, db.TeamInvitation{}, false
+	}
+
+	invitation, err := h.q.GetInvitationByID(r.Context(), invitationID)
+	if err != nil || !invitation.UserID.Valid || invitation.UserID.Int64 != userID {
+		json_resp.RespondError(w, http.StatusNotFound, "NOT_FOUND", "invitation not found")
+		return 0, db.TeamInvitation{}, false
+	}
+
+	if invitation.Status != db.TeamInvitationsStatusPending {
+		json_resp.RespondError(w, http.StatusConflict, "CONFLICT", "invitation has already been "+string(invitation.Status))
+		return 0, db.TeamInvitation{}, false
+	}
+
+	if time.Now().After(invitation.ExpiresAt) {
+		json_resp.RespondError(w, http.StatusConflict, "CONFLICT", "invitation has expired")
+		return 0, db.TeamInvitation{}, false
+	}
+
+	return userID, invitation, true
+}
+
+// respondToInvitation guards against a concurrent accept/decline or the
+// invitation expiring between the lookup and the update.
+func (h *TeamHandlers) respondToInvitation(w http.ResponseWriter, r *http.Request, q *db.Queries, invitationID int64, status db.TeamInvitationsStatus) bool {
+	updated, err := q.RespondToInvitation(r.Context(), db.RespondToInvitationParams{
+		Status: status,
+		ID:     invitationID,
+	})
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update invitation")
+		return false
+	}
+	if updated == 0 {
+		json_resp.RespondError(w, http.StatusConflict, "CONFLICT", "invitation is no longer pending")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+	json_resp "github.com/egor_lukyanovich/moon_test_application/pkg/json"
+	id_helper "github.com/egor_lukyanovich/moon_test_application/pkg/routing"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/validation"
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+type MemberHandlers struct {
+	q     *db.Queries
+	db    *sql.DB
+	cache taskListCache
+}
+
+func NewMemberHandlers(q *db.Queries, database *sql.DB, redisClient *redis.Client) *MemberHandlers {
+	return &MemberHandlers{q: q, db: database, cache: taskListCache{redis: redisClient}}
+}
+
+func (h *MemberHandlers) ListMembers(w http.ResponseWriter, r *http.Request) {
+	userID, teamID, ok := teamRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if !id_helper.CheckTeamRole(r.Context(), h.q, teamID, userID) {
+		json_resp.RespondError(w, http.StatusForbidden, "FORBIDDEN", "you are not a member of this team")
+		return
+	}
+
+	members, err := h.q.ListTeamMembers(r.Context(), teamID)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to fetch members")
+		return
+	}
+	if members == nil {
+		members = []db.ListTeamMembersRow{}
+	}
+
+	json_resp.RespondJSON(w, http.StatusOK, members)
+}
+
+// UpdateMemberRole is owner-only, so only owners can promote to admin or owner.
+func (h *MemberHandlers) UpdateMemberRole(w http.ResponseWriter, r *http.Request) {
+	userID, teamID, ok := teamRequest(w, r)
+	if !ok {
+		return
+	}
+
+	memberID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid user id")
+		return
+	}
+
+	if !id_helper.CheckTeamRole(r.Context(), h.q, teamID, userID, "owner") {
+		json_resp.RespondError(w, http.StatusForbidden, "FORBIDDEN", "only team owner can change roles")
+		return
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json_resp.RespondError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	v := validation.New()
+	v.Required("role", req.Role)
+	v.OneOf("role", req.Role, memberRoles...)
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+	newRole := db.TeamMembersRole(req.Role)
+
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to start tx")
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.q.WithTx(tx)
+
+	if !lockTeamMembers(w, r, qtx, teamID) {
+		return
+	}
+
+	// Checked again under the lock: the caller may have been demoted since.
+	if !id_helper.CheckTeamRole(r.Context(), qtx, teamID, userID, "owner") {
+		json_resp.RespondError(w, http.StatusForbidden, "FORBIDDEN", "only team owner can change roles")
+		return
+	}
+
+	currentRole, err := qtx.GetUserRoleInTeam(r.Context(), db.GetUserRoleInTeamParams{TeamID: teamID, UserID: memberID})
+	if err != nil {
+		json_resp.RespondError(w, http.StatusNotFound, "NOT_FOUND", "member not found")
+		return
+	}
+
+	if currentRole == db.TeamMembersRoleOwner && newRole != db.TeamMembersRoleOwner {
+		if !h.hasAnotherOwner(w, r, qtx, teamID) {
+			return
+		}
+	}
+
+	err = qtx.UpdateTeamMemberRole(r.Context(), db.UpdateTeamMemberRoleParams{
+		Role:   newRole,
+		TeamID: teamID,
+		UserID: memberID,
+	})
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update role")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to commit tx")
+		return
+	}
+
+	json_resp.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id": memberID,
+		"role":    newRole,
+	})
+}
+
+// RemoveMember lets owners remove anyone and admins remove plain members.
+// Removing yourself is the same as leaving the team.
+func (h *MemberHandlers) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID, teamID, ok := teamRequest(w, r)
+	if !ok {
+		return
+	}
+
+	memberID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid user id")
+		return
+	}
+
+	h.removeMember(w, r, teamID, userID, memberID)
+}
+
+func (h *MemberHandlers) LeaveTeam(w http.ResponseWriter, r *http.Request) {
+	userID, teamID, ok := teamRequest(w, r)
+	if !ok {
+		return
+	}
+
+	h.removeMember(w, r, teamID, userID, userID)
+}
+
+// removeMember drops the membership and unassigns the member's tasks in the
+// team, or hands them over to reassign_to when it is given. The roles are
+// read under the team lock, so the checks still hold at commit.
+func (h *MemberHandlers) removeMember(w http.ResponseWriter, r *http.Request, teamID, actorID, memberID int64) {
+	var req struct {
+		ReassignTo *int64 `json:"reassign_to"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		json_resp.RespondError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to start tx")
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.q.WithTx(tx)
+
+	if !lockTeamMembers(w, r, qtx, teamID) {
+		return
+	}
+
+	actorRole, err := qtx.GetUserRoleInTeam(r.Context(), db.GetUserRoleInTeamParams{TeamID: teamID, UserID: actorID})
+	if err != nil {
+		json_resp.RespondError(w, http.StatusForbidden, "FORBIDDEN", "you are not a member of this team")
+		return
+	}
+
+	role := actorRole
+	if memberID != actorID {
+		role, err = qtx.GetUserRoleInTeam(r.Context(), db.GetUserRoleInTeamParams{TeamID: teamID, UserID: memberID})
+		if err != nil {
+			json_resp.RespondError(w, http.StatusNotFound, "NOT_FOUND", "member not found")
+			return
+		}
+
+		allowed := actorRole == db.TeamMembersRoleOwner ||
+			(actorRole == db.TeamMembersRoleAdmin && role == db.TeamMembersRoleMember)
+		if !allowed {
+			json_resp.RespondError(w, http.StatusForbidden, "FORBIDDEN", "you cannot remove this member")
+			return
+		}
+	}
+
+	var newAssignee sql.NullInt64
+	if req.ReassignTo != nil {
+		v := validation.New()
+		v.Positive("reassign_to", *req.ReassignTo)
+		v.Check(*req.ReassignTo != memberID, "reassign_to", "must not be the member being removed")
+		v.Check(id_helper.CheckTeamRole(r.Context(), qtx, teamID, *req.ReassignTo), "reassign_to", "must be a member of the team")
+		if !v.Valid() {
+			json_resp.RespondValidationError(w, v.Errors())
+			return
+		}
+		newAssignee = sql.NullInt64{Int64: *req.ReassignTo, Valid: true}
+	}
+
+	if role == db.TeamMembersRoleOwner && !h.hasAnotherOwner(w, r, qtx, teamID) {
+		return
+	}
+
+	tasks, err := qtx.ListTeamTasksByAssignee(r.Context(), db.ListTeamTasksByAssigneeParams{
+		TeamID:     teamID,
+		AssigneeID: sql.NullInt64{Int64: memberID, Valid: true},
+	})
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to fetch assigned tasks")
+		return
+	}
+
+	taskIDs := []int64{}
+	for _, oldTask := range tasks {
+		newTask := oldTask
+		newTask.AssigneeID = newAssignee
+
+		err = qtx.PatchTask(r.Context(), db.PatchTaskParams{
+			ID:          oldTask.ID,
+			SetAssignee: true,
+			AssigneeID:  newAssignee,
+		})
+		if err != nil {
+			json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update task")
+			return
+		}
+
+		if err := recordTaskChanges(r.Context(), qtx, actorID, oldTask, newTask); err != nil {
+			json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to write task history")
+			return
+		}
+
+		taskIDs = append(taskIDs, oldTask.ID)
+	}
+
+	err = qtx.RemoveTeamMember(r.Context(), db.RemoveTeamMemberParams{TeamID: teamID, UserID: memberID})
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to remove member")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to commit tx")
+		return
+	}
+
+	if len(taskIDs) > 0 {
+		h.cache.Invalidate(r.Context(), teamID)
+	}
+
+	json_resp.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"status":   "removed",
+		"user_id":  memberID,
+		"task_ids": taskIDs,
+	})
+}
+
+// lockTeamMembers serializes membership changes of a team. Roles must be read
+// after it, inside the same tx, for the checks on them to hold at commit.
+func lockTeamMembers(w http.ResponseWriter, r *http.Request, qtx *db.Queries, teamID int64) bool {
+	_, err := qtx.LockTeam(r.Context(), teamID)
+	if errors.Is(err, sql.ErrNoRows) {
+		json_resp.RespondError(w, http.StatusForbidden, "FORBIDDEN", "you are not a member of this team")
+		return false
+	}
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to lock team")
+		return false
+	}
+	return true
+}
+
+// hasAnotherOwner locks the team's owner rows, so two owners stepping down
+// concurrently cannot leave the team without one.
+func (h *MemberHandlers) hasAnotherOwner(w http.ResponseWriter, r *http.Request, qtx *db.Queries, teamID int64) bool {
+	owners, err := qtx.CountTeamOwners(r.Context(), teamID)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to count owners")
+		return false
+	}
+	if owners <= 1 {
+		json_resp.RespondError(w, http.StatusConflict, "CONFLICT", "team must keep at least one owner; transfer ownership first")
+		return false
+	}
+	return true
+}
+
+func teamRequest(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	userID, ok := id_helper.GetUserIDHelper(r.Context())
+	if !ok {
+		json_resp.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+		return 0, 0, false
+	}
+
+	teamID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid team id")
+		return 0, 0, false
+	}
+
+	return userID, teamID, true
+}
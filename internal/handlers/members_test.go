@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+	id_helper "github.com/egor_lukyanovich/moon_test_application/pkg/routing"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestTeamMembers(t *testing.T) {
+	database, cleanupDB := setupTestDBWithTasks(t)
+	defer cleanupDB()
+	rdb, cleanupRedis := setupTestRedis(t)
+	defer cleanupRedis()
+
+	ctx := context.Background()
+	queries := db.New(database)
+	memberHandlers := NewMemberHandlers(queries, database, rdb)
+
+	createUser := func(email string) int64 {
+		res, _ := queries.CreateUser(ctx, db.CreateUserParams{Email: email, PasswordHash: "hash"})
+		id, _ := res.LastInsertId()
+		return id
+	}
+	ownerID := createUser("owner@example.com")
+	adminID := createUser("admin@example.com")
+	memberID := createUser("member@example.com")
+	otherID := createUser("other@example.com")
+	outsiderID := createUser("outsider@example.com")
+
+	resTeam, _ := queries.CreateTeam(ctx, db.CreateTeamParams{Name: "Members Team", CreatedBy: ownerID})
+	teamID, _ := resTeam.LastInsertId()
+
+	_ = queries.AddTeamMember(ctx, db.AddTeamMemberParams{TeamID: teamID, UserID: ownerID, Role: "owner"})
+	_ = queries.AddTeamMember(ctx, db.AddTeamMemberParams{TeamID: teamID, UserID: adminID, Role: "admin"})
+	_ = queries.AddTeamMember(ctx, db.AddTeamMemberParams{TeamID: teamID, UserID: memberID, Role: "member"})
+	_ = queries.AddTeamMember(ctx, db.AddTeamMemberParams{TeamID: teamID, UserID: otherID, Role: "member"})
+
+	resTask, _ := queries.CreateTask(ctx, db.CreateTaskParams{
+		Title: "Assigned", Status: "todo", TeamID: teamID, CreatedBy: ownerID,
+		AssigneeID: sql.NullInt64{Int64: memberID, Valid: true},
+	})
+	taskID, _ := resTask.LastInsertId()
+
+	r := chi.NewRouter()
+	r.Get("/teams/{id}/members", memberHandlers.ListMembers)
+	r.Patch("/teams/{id}/members/{userID}", memberHandlers.UpdateMemberRole)
+	r.Delete("/teams/{id}/members/{userID}", memberHandlers.RemoveMember)
+	r.Post("/teams/{id}/leave", memberHandlers.LeaveTeam)
+
+	teamURL := "/teams/" + strconv.FormatInt(teamID, 10)
+	memberURL := func(id int64) string {
+		return teamURL + "/members/" + strconv.FormatInt(id, 10)
+	}
+
+	do := func(method, url string, body []byte, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req = req.WithContext(id_helper.WithUserID(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := do(http.MethodGet, teamURL+"/members", nil, outsiderID); rr.Code != http.StatusForbidden {
+		t.Errorf("expected outsider to get 403, got %d", rr.Code)
+	}
+
+	rrList := do(http.MethodGet, teamURL+"/members", nil, memberID)
+	if rrList.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rrList.Code, rrList.Body.String())
+	}
+	var members []db.ListTeamMembersRow
+	json.NewDecoder(rrList.Body).Decode(&members)
+	if len(members) != 4 {
+		t.Errorf("expected 4 members, got %d", len(members))
+	}
+
+	if rr := do(http.MethodPatch, memberURL(memberID), []byte(`{"role": "admin"}`), adminID); rr.Code != http.StatusForbidden {
+		t.Errorf("expected admin promoting to admin to get 403, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPatch, memberURL(memberID), []byte(`{"role": "boss"}`), ownerID); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected unknown role to get 422, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPatch, memberURL(ownerID), []byte(`{"role": "member"}`), ownerID); rr.Code != http.StatusConflict {
+		t.Errorf("expected last owner demotion to get 409, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPatch, memberURL(otherID), []byte(`{"role": "admin"}`), ownerID); rr.Code != http.StatusOK {
+		t.Errorf("expected owner to promote member, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := do(http.MethodDelete, memberURL(otherID), nil, adminID); rr.Code != http.StatusForbidden {
+		t.Errorf("expected admin removing another admin to get 403, got %d", rr.Code)
+	}
+	if rr := do(http.MethodDelete, memberURL(memberID), []byte(`{"reassign_to": `+strconv.FormatInt(outsiderID, 10)+`}`), outsiderID); rr.Code != http.StatusForbidden {
+		t.Errorf("expected outsider to get 403 before reassign_to is checked, got %d", rr.Code)
+	}
+	if rr := do(http.MethodDelete, memberURL(memberID), []byte(`{"reassign_to": `+strconv.FormatInt(outsiderID, 10)+`}`), adminID); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected reassigning to outsider to get 422, got %d", rr.Code)
+	}
+
+	rrRemove := do(http.MethodDelete, memberURL(memberID), []byte(`{"reassign_to": `+strconv.FormatInt(adminID, 10)+`}`), adminID)
+	if rrRemove.Code != http.StatusOK {
+		t.Fatalf("expected admin to remove member, got %d: %s", rrRemove.Code, rrRemove.Body.String())
+	}
+	if id_helper.CheckTeamRole(ctx, queries, teamID, memberID) {
+		t.Errorf("expected member to be removed")
+	}
+
+	task, _ := queries.GetTaskByID(ctx, taskID)
+	if task.AssigneeID.Int64 != adminID {
+		t.Errorf("expected task to be reassigned to admin, got %v", task.AssigneeID)
+	}
+	history, _ := queries.ListTaskHistory(ctx, taskID)
+	if len(history) != 1 || history[0].ChangeType != "assignee_update" {
+		t.Errorf("expected an assignee_update history entry, got %+v", history)
+	}
+
+	if rr := do(http.MethodPost, teamURL+"/leave", nil, ownerID); rr.Code != http.StatusConflict {
+		t.Errorf("expected last owner leaving to get 409, got %d", rr.Code)
+	}
+
+	if rr := do(http.MethodPost, teamURL+"/leave", nil, adminID); rr.Code != http.StatusOK {
+		t.Fatalf("expected admin to leave, got %d: %s", rr.Code, rr.Body.String())
+	}
+	task, _ = queries.GetTaskByID(ctx, taskID)
+	if task.AssigneeID.Valid {
+		t.Errorf("expected task to be unassigned after assignee left, got %v", task.AssigneeID)
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/validation"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+)
+
+// taskCursor points at the last task of a page: its sort key value and id.
+// The sort is recorded too, since a cursor only makes sense for the order
+// it was produced in.
+type taskCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int64  `json:"i"`
+}
+
+func sortName(sort db.TaskSort) string {
+	if sort.Desc {
+		return string(sort.Field) + ":desc"
+	}
+	return string(sort.Field) + ":asc"
+}
+
+func sortValue(task db.Task, field db.TaskSortField) string {
+	switch field {
+	case db.TaskSortUpdatedAt:
+		return task.UpdatedAt.Time.UTC().Format(time.RFC3339Nano)
+	case db.TaskSortTitle:
+		return task.Title
+	case db.TaskSortStatus:
+		return task.Status
+	default:
+		return task.CreatedAt.Time.UTC().Format(time.RFC3339Nano)
+	}
+}
+
+// encodeCursor keeps the cursor opaque to clients: they must pass it back as is.
+func encodeCursor(task db.Task, sort db.TaskSort) string {
+	data, _ := json.Marshal(taskCursor{Sort: sortName(sort), Value: sortValue(task, sort.Field), ID: task.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(raw string, sort db.TaskSort) (db.TaskCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return db.TaskCursor{}, err
+	}
+
+	var c taskCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return db.TaskCursor{}, err
+	}
+	if c.ID <= 0 {
+		return db.TaskCursor{}, errors.New("incomplete cursor")
+	}
+	if c.Sort != sortName(sort) {
+		return db.TaskCursor{}, errors.New("cursor belongs to a different sort order")
+	}
+
+	switch sort.Field {
+	case db.TaskSortCreatedAt, db.TaskSortUpdatedAt:
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return db.TaskCursor{}, err
+		}
+		return db.TaskCursor{Value: t, ID: c.ID}, nil
+	default:
+		return db.TaskCursor{Value: c.Value, ID: c.ID}, nil
+	}
+}
+
+type pageParams struct {
+	Size         int
+	Cursor       *db.TaskCursor
+	IncludeTotal bool
+}
+
+func parsePageParams(v *validation.Validator, query url.Values, sort db.TaskSort) pageParams {
+	p := pageParams{Size: defaultPageSize}
+
+	if raw := query.Get("page_size"); raw != "" {
+		size, err := strconv.Atoi(raw)
+		v.Check(err == nil && size >= 1 && size <= maxPageSize, "page_size",
+			"must be an integer between 1 and "+strconv.Itoa(maxPageSize))
+		p.Size = size
+	}
+
+	if raw := query.Get("cursor"); raw != "" {
+		c, err := decodeCursor(raw, sort)
+		v.Check(err == nil, "cursor", "is malformed or does not match the requested sort")
+		p.Cursor = &c
+	}
+
+	if raw := query.Get("include_total"); raw != "" {
+		include, err := strconv.ParseBool(raw)
+		v.Check(err == nil, "include_total", "must be a boolean")
+		p.IncludeTotal = include
+	}
+
+	return p
+}
+
+type taskPage struct {
+	Items      []db.Task `json:"items"`
+	NextCursor *string   `json:"next_cursor"`
+	Total      *int64    `json:"total,omitempty"`
+}
+
+// newTaskPage expects up to size+1 tasks: the extra one only signals that
+// another page exists and is not returned.
+func newTaskPage(tasks []db.Task, size int, sort db.TaskSort) taskPage {
+	page := taskPage{Items: tasks}
+	if page.Items == nil {
+		page.Items = []db.Task{}
+	}
+
+	if len(page.Items) > size {
+		page.Items = page.Items[:size]
+		next := encodeCursor(page.Items[size-1], sort)
+		page.NextCursor = &next
+	}
+	return page
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+)
+
+func TestTaskCursor(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	task := db.Task{ID: 42, Title: "Deploy", CreatedAt: sql.NullTime{Time: createdAt, Valid: true}}
+	newest := db.TaskSort{Field: db.TaskSortCreatedAt, Desc: true}
+	byTitle := db.TaskSort{Field: db.TaskSortTitle}
+
+	c, err := decodeCursor(encodeCursor(task, newest), newest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if at, ok := c.Value.(time.Time); c.ID != 42 || !ok || !at.Equal(createdAt) {
+		t.Errorf("cursor did not round-trip, got %+v", c)
+	}
+
+	c, err = decodeCursor(encodeCursor(task, byTitle), byTitle)
+	if err != nil || c.Value != "Deploy" {
+		t.Errorf("expected title cursor, got %+v (%v)", c, err)
+	}
+
+	if _, err := decodeCursor(encodeCursor(task, byTitle), newest); err == nil {
+		t.Errorf("expected a cursor of another sort order to be rejected")
+	}
+
+	for _, raw := range []string{"%%%", "bm90IGpzb24", "e30"} {
+		if _, err := decodeCursor(raw, newest); err == nil {
+			t.Errorf("expected %q to be rejected", raw)
+		}
+	}
+}
+
+func TestNewTaskPage(t *testing.T) {
+	tasks := []db.Task{{ID: 3}, {ID: 2}, {ID: 1}}
+	newest := db.TaskSort{Field: db.TaskSortCreatedAt, Desc: true}
+
+	page := newTaskPage(tasks, 2, newest)
+	if len(page.Items) != 2 || page.NextCursor == nil {
+		t.Errorf("expected 2 items and a next cursor, got %d items, cursor %v", len(page.Items), page.NextCursor)
+	}
+
+	last := newTaskPage(tasks[:2], 2, newest)
+	if len(last.Items) != 2 || last.NextCursor != nil {
+		t.Errorf("expected the last page without a next cursor, got %v", last.NextCursor)
+	}
+
+	if empty := newTaskPage(nil, 2, newest); empty.Items == nil {
+		t.Errorf("expected an empty slice, not nil")
+	}
+}
@@ -1,10 +1,25 @@
 package handlers
 
 import (
+	"database/sql"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/egor_lukyanovich/moon_test_application/internal/db"
 	json_resp "github.com/egor_lukyanovich/moon_test_application/pkg/json"
+	id_helper "github.com/egor_lukyanovich/moon_test_application/pkg/routing"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/validation"
+)
+
+const (
+	defaultTopUsers = 3
+	maxTopUsers     = 100
+
+	topMetricCreated   = "created"
+	topMetricCompleted = "completed"
+	topMetricAssigned  = "assigned"
 )
 
 type StatsHandlers struct {
@@ -15,8 +30,100 @@ func NewStatsHandlers(q *db.Queries) *StatsHandlers {
 	return &StatsHandlers{q: q}
 }
 
+type statsScope struct {
+	memberID sql.NullInt64
+	teamID   sql.NullInt64
+}
+
+func (h *StatsHandlers) resolveScope(w http.ResponseWriter, r *http.Request) (statsScope, bool) {
+	userID, ok := id_helper.GetUserIDHelper(r.Context())
+	if !ok {
+		json_resp.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+		return statsScope{}, false
+	}
+
+	var scope statsScope
+	if !id_helper.IsSystemAdmin(r.Context(), h.q, userID) {
+		scope.memberID = sql.NullInt64{Int64: userID, Valid: true}
+	}
+
+	v := validation.New()
+	teamID := parseIDParam(v, r.URL.Query(), "team_id")
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return statsScope{}, false
+	}
+
+	if teamID.Valid {
+		if scope.memberID.Valid && !id_helper.CheckTeamRole(r.Context(), h.q, teamID.Int64, userID) {
+			json_resp.RespondError(w, http.StatusForbidden, "FORBIDDEN", "you are not a member of this team")
+			return statsScope{}, false
+		}
+		scope.teamID = teamID
+	}
+
+	return scope, true
+}
+
+type statsWindow struct {
+	from time.Time
+	to   time.Time
+}
+
+func parseQueryTime(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func parseStatsWindow(v *validation.Validator, r *http.Request, defaultFrom func(to time.Time) time.Time) statsWindow {
+	window := statsWindow{to: time.Now().UTC()}
+
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		to, err := parseQueryTime(toStr, true)
+		v.Check(err == nil, "to", "must be a date (YYYY-MM-DD) or RFC3339 timestamp")
+		window.to = to
+	}
+
+	window.from = defaultFrom(window.to)
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		from, err := parseQueryTime(fromStr, false)
+		v.Check(err == nil, "from", "must be a date (YYYY-MM-DD) or RFC3339 timestamp")
+		window.from = from
+	}
+
+	v.Check(window.from.Before(window.to), "from", "must be before to")
+	return window
+}
+
 func (h *StatsHandlers) GetTeamStats(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.q.GetTeamStats(r.Context())
+	scope, ok := h.resolveScope(w, r)
+	if !ok {
+		return
+	}
+
+	v := validation.New()
+	window := parseStatsWindow(v, r, func(to time.Time) time.Time { return to.AddDate(0, 0, -7) })
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
+	stats, err := h.q.GetTeamStats(r.Context(), db.GetTeamStatsParams{
+		FromDate: window.from,
+		ToDate:   window.to,
+		MemberID: scope.memberID,
+		TeamID:   scope.teamID,
+	})
 	if err != nil {
 		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to fetch team stats")
 		return
@@ -29,22 +136,98 @@ func (h *StatsHandlers) GetTeamStats(w http.ResponseWriter, r *http.Request) {
 	json_resp.RespondJSON(w, http.StatusOK, stats)
 }
 
+type topUser struct {
+	TeamID    int64
+	UserID    int64
+	TaskCount int64
+	RankNum   interface{}
+}
+
 func (h *StatsHandlers) GetTopUsers(w http.ResponseWriter, r *http.Request) {
-	topUsers, err := h.q.GetTopUsersPerTeam(r.Context())
-	if err != nil {
-		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to fetch top users")
+	scope, ok := h.resolveScope(w, r)
+	if !ok {
+		return
+	}
+
+	v := validation.New()
+	window := parseStatsWindow(v, r, func(to time.Time) time.Time { return to.AddDate(0, -1, 0) })
+
+	topN := int64(defaultTopUsers)
+	if topStr := r.URL.Query().Get("top"); topStr != "" {
+		n, err := strconv.ParseInt(topStr, 10, 32)
+		v.Check(err == nil && n >= 1 && n <= maxTopUsers, "top", fmt.Sprintf("must be an integer between 1 and %d", maxTopUsers))
+		topN = n
+	}
+
+	metric := r.URL.Query().Get("metric")
+	if metric == "" {
+		metric = topMetricCreated
+	}
+	v.OneOf("metric", metric, topMetricCreated, topMetricCompleted, topMetricAssigned)
+
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
 		return
 	}
 
-	if topUsers == nil {
-		topUsers = []db.GetTopUsersPerTeamRow{}
+	topUsers := []topUser{}
+	var err error
+	switch metric {
+	case topMetricCreated:
+		var rows []db.GetTopCreatorsPerTeamRow
+		rows, err = h.q.GetTopCreatorsPerTeam(r.Context(), db.GetTopCreatorsPerTeamParams{
+			FromDate: window.from,
+			ToDate:   window.to,
+			MemberID: scope.memberID,
+			TeamID:   scope.teamID,
+			TopN:     int32(topN),
+		})
+		for _, row := range rows {
+			topUsers = append(topUsers, topUser{row.TeamID, row.UserID, row.TaskCount, row.RankNum})
+		}
+	case topMetricCompleted:
+		var rows []db.GetTopCompletersPerTeamRow
+		rows, err = h.q.GetTopCompletersPerTeam(r.Context(), db.GetTopCompletersPerTeamParams{
+			FromDate: window.from,
+			ToDate:   window.to,
+			MemberID: scope.memberID,
+			TeamID:   scope.teamID,
+			TopN:     int32(topN),
+		})
+		for _, row := range rows {
+			topUsers = append(topUsers, topUser{row.TeamID, row.UserID.Int64, row.TaskCount, row.RankNum})
+		}
+	case topMetricAssigned:
+		var rows []db.GetTopAssigneesPerTeamRow
+		rows, err = h.q.GetTopAssigneesPerTeam(r.Context(), db.GetTopAssigneesPerTeamParams{
+			FromDate: window.from,
+			ToDate:   window.to,
+			MemberID: scope.memberID,
+			TeamID:   scope.teamID,
+			TopN:     int32(topN),
+		})
+		for _, row := range rows {
+			topUsers = append(topUsers, topUser{row.TeamID, row.UserID.Int64, row.TaskCount, row.RankNum})
+		}
+	}
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to fetch top users")
+		return
 	}
 
 	json_resp.RespondJSON(w, http.StatusOK, topUsers)
 }
 
 func (h *StatsHandlers) GetInvalidTasks(w http.ResponseWriter, r *http.Request) {
-	invalidTasks, err := h.q.FindInvalidTasks(r.Context())
+	scope, ok := h.resolveScope(w, r)
+	if !ok {
+		return
+	}
+
+	invalidTasks, err := h.q.FindInvalidTasks(r.Context(), db.FindInvalidTasksParams{
+		MemberID: scope.memberID,
+		TeamID:   scope.teamID,
+	})
 	if err != nil {
 		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to fetch invalid tasks")
 		return
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+	id_helper "github.com/egor_lukyanovich/moon_test_application/pkg/routing"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/validation"
+)
+
+func TestTeamStatsScope(t *testing.T) {
+	database, cleanupDB := setupTestDBWithTasks(t)
+	defer cleanupDB()
+
+	queries := db.New(database)
+	statsHandlers := NewStatsHandlers(queries)
+
+	newUser := func(email string) int64 {
+		res, _ := queries.CreateUser(context.Background(), db.CreateUserParams{Email: email, PasswordHash: "hash"})
+		id, _ := res.LastInsertId()
+		return id
+	}
+	newTeam := func(name string, ownerID int64) int64 {
+		res, _ := queries.CreateTeam(context.Background(), db.CreateTeamParams{Name: name, CreatedBy: ownerID})
+		id, _ := res.LastInsertId()
+		_ = queries.AddTeamMember(context.Background(), db.AddTeamMemberParams{TeamID: id, UserID: ownerID, Role: "owner"})
+		return id
+	}
+
+	aliceID := newUser("alice@example.com")
+	bobID := newUser("bob@example.com")
+	adminID := newUser("admin@example.com")
+	_, _ = database.Exec("UPDATE users SET is_admin = TRUE WHERE id = ?", adminID)
+
+	newTeam("Alice Team", aliceID)
+	bobTeamID := newTeam("Bob Team", bobID)
+
+	get := func(url string, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req = req.WithContext(id_helper.WithUserID(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		statsHandlers.GetTeamStats(rr, req)
+		return rr
+	}
+
+	var stats []map[string]interface{}
+	rrAlice := get("/stats/teams", aliceID)
+	json.NewDecoder(rrAlice.Body).Decode(&stats)
+	if rrAlice.Code != http.StatusOK || len(stats) != 1 || stats[0]["TeamName"] != "Alice Team" {
+		t.Errorf("expected only alice's team, got %v: %v", rrAlice.Code, stats)
+	}
+
+	rrForeign := get("/stats/teams?team_id="+strconv.FormatInt(bobTeamID, 10), aliceID)
+	if rrForeign.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for foreign team_id, got %v", rrForeign.Code)
+	}
+
+	if rr := get("/stats/teams?team_id=abc", aliceID); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for malformed team_id, got %v", rr.Code)
+	}
+
+	stats = nil
+	rrAdmin := get("/stats/teams", adminID)
+	json.NewDecoder(rrAdmin.Body).Decode(&stats)
+	if rrAdmin.Code != http.StatusOK || len(stats) != 2 {
+		t.Errorf("expected system admin to see all teams, got %v: %v", rrAdmin.Code, stats)
+	}
+}
+
+func TestParseStatsWindow(t *testing.T) {
+	weekBefore := func(to time.Time) time.Time { return to.AddDate(0, 0, -7) }
+
+	v := validation.New()
+	window := parseStatsWindow(v, httptest.NewRequest(http.MethodGet, "/stats/teams?from=2024-01-01&to=2024-01-31", nil), weekBefore)
+	if !v.Valid() {
+		t.Fatalf("unexpected validation errors: %v", v.Errors())
+	}
+	if !window.from.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !window.to.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected date-only bounds to cover the whole last day, got %v - %v", window.from, window.to)
+	}
+
+	v = validation.New()
+	window = parseStatsWindow(v, httptest.NewRequest(http.MethodGet, "/stats/teams?to=2024-03-10T12:00:00Z", nil), weekBefore)
+	if !v.Valid() || !window.from.Equal(time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected default window to end at to, got %v - %v (%v)", window.from, window.to, v.Errors())
+	}
+
+	v = validation.New()
+	parseStatsWindow(v, httptest.NewRequest(http.MethodGet, "/stats/teams?from=2024-02-01&to=2024-01-01", nil), weekBefore)
+	parseStatsWindow(v, httptest.NewRequest(http.MethodGet, "/stats/teams?to=yesterday", nil), weekBefore)
+	if len(v.Errors()) != 2 {
+		t.Errorf("expected errors for inverted range and bad date, got %v", v.Errors())
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const taskListCacheTTL = 5 * time.Minute
+
+// taskListCache stores ListTasks pages under keys that embed a per-team version.
+// Bumping the version on any write makes every cached variant of the team unreachable at once;
+// the orphaned entries simply expire with their TTL.
+type taskListCache struct {
+	redis *redis.Client
+}
+
+func (c taskListCache) versionKey(teamID int64) string {
+	return fmt.Sprintf("tasks:t:%d:ver", teamID)
+}
+
+func (c taskListCache) key(ctx context.Context, teamID int64, variant string) (string, error) {
+	version, err := c.redis.Get(ctx, c.versionKey(teamID)).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+	return fmt.Sprintf("tasks:t:%d:v:%d:%s", teamID, version, variant), nil
+}
+
+func (c taskListCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	data, err := c.redis.Get(ctx, key).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+func (c taskListCache) Set(ctx context.Context, key string, data []byte) {
+	if err := c.redis.Set(ctx, key, data, taskListCacheTTL).Err(); err != nil {
+		log.Println("tasks cache write failed:", err)
+	}
+}
+
+func (c taskListCache) Invalidate(ctx context.Context, teamID int64) {
+	if err := c.redis.Incr(ctx, c.versionKey(teamID)).Err(); err != nil {
+		log.Println("tasks cache invalidation failed:", err)
+	}
+}
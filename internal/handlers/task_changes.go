@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+)
+
+func assigneeValue(assignee sql.NullInt64) sql.NullString {
+	if !assignee.Valid {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: strconv.FormatInt(assignee.Int64, 10), Valid: true}
+}
+
+func taskChanges(before, after db.Task) []db.CreateTaskHistoryParams {
+	var changes []db.CreateTaskHistoryParams
+	add := func(changeType string, oldValue, newValue sql.NullString) {
+		if oldValue == newValue {
+			return
+		}
+		changes = append(changes, db.CreateTaskHistoryParams{
+			TaskID:     before.ID,
+			ChangeType: changeType,
+			OldValue:   oldValue,
+			NewValue:   newValue,
+		})
+	}
+
+	add("title_update",
+		sql.NullString{String: before.Title, Valid: true},
+		sql.NullString{String: after.Title, Valid: true})
+	add("description_update", before.Description, after.Description)
+	add("status_update",
+		sql.NullString{String: before.Status, Valid: true},
+		sql.NullString{String: after.Status, Valid: true})
+	add("assignee_update", assigneeValue(before.AssigneeID), assigneeValue(after.AssigneeID))
+
+	return changes
+}
+
+func recordTaskChanges(ctx context.Context, qtx *db.Queries, userID int64, before, after db.Task) error {
+	for _, change := range taskChanges(before, after) {
+		change.ChangedBy = sql.NullInt64{Int64: userID, Valid: true}
+		if err := qtx.CreateTaskHistory(ctx, change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
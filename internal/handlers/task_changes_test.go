@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+)
+
+func TestTaskChanges(t *testing.T) {
+	before := db.Task{
+		ID:         1,
+		Title:      "Old",
+		Status:     "todo",
+		AssigneeID: sql.NullInt64{Int64: 7, Valid: true},
+	}
+
+	if changes := taskChanges(before, before); len(changes) != 0 {
+		t.Errorf("expected no changes for identical tasks, got %v", changes)
+	}
+
+	after := before
+	after.Title = "New"
+	after.Description = sql.NullString{String: "details", Valid: true}
+	after.Status = "done"
+	after.AssigneeID = sql.NullInt64{}
+
+	changes := taskChanges(before, after)
+	if len(changes) != 4 {
+		t.Fatalf("expected 4 changes, got %d: %v", len(changes), changes)
+	}
+
+	expected := map[string][2]sql.NullString{
+		"title_update":       {{String: "Old", Valid: true}, {String: "New", Valid: true}},
+		"description_update": {{}, {String: "details", Valid: true}},
+		"status_update":      {{String: "todo", Valid: true}, {String: "done", Valid: true}},
+		"assignee_update":    {{String: "7", Valid: true}, {}},
+	}
+	for _, change := range changes {
+		want, ok := expected[change.ChangeType]
+		if !ok {
+			t.Errorf("unexpected change type %q", change.ChangeType)
+			continue
+		}
+		if change.TaskID != before.ID || change.OldValue != want[0] || change.NewValue != want[1] {
+			t.Errorf("%s: expected %v -> %v, got %v -> %v", change.ChangeType, want[0], want[1], change.OldValue, change.NewValue)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/validation"
+)
+
+const maxSearchLength = 255
+
+var taskSortFields = []string{
+	string(db.TaskSortCreatedAt),
+	string(db.TaskSortUpdatedAt),
+	string(db.TaskSortTitle),
+	string(db.TaskSortStatus),
+}
+
+// parseTaskFilter reads every task list filter except the team scope, which
+// each endpoint resolves on its own. The result is normalized (statuses in
+// workflow order, search terms lowercased and sorted, times in UTC) so that
+// equivalent queries share a cache entry.
+func parseTaskFilter(v *validation.Validator, query url.Values) db.TaskFilter {
+	var f db.TaskFilter
+
+	requested := map[string]bool{}
+	for _, raw := range query["status"] {
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s != "" && !requested[s] {
+				validateStatus(v, s)
+				requested[s] = true
+				f.Statuses = append(f.Statuses, s)
+			}
+		}
+	}
+	sort.Slice(f.Statuses, func(i, j int) bool {
+		return db.StatusSortKey(f.Statuses[i]) < db.StatusSortKey(f.Statuses[j])
+	})
+
+	f.AssigneeID = parseIDParam(v, query, "assignee_id")
+	f.CreatedBy = parseIDParam(v, query, "created_by")
+
+	if raw := query.Get("unassigned"); raw != "" {
+		unassigned, err := strconv.ParseBool(raw)
+		v.Check(err == nil, "unassigned", "must be a boolean")
+		v.Check(!unassigned || !f.AssigneeID.Valid, "unassigned", "cannot be combined with assignee_id")
+		f.Unassigned = unassigned
+	}
+
+	f.CreatedFrom, f.CreatedTo = parseTimeRange(v, query, "created")
+	f.UpdatedFrom, f.UpdatedTo = parseTimeRange(v, query, "updated")
+
+	if raw := query.Get("q"); raw != "" {
+		v.MaxLength("q", raw, maxSearchLength)
+		f.SearchTerms = searchTerms(raw)
+		v.Check(len(f.SearchTerms) > 0, "q", "must contain at least one word")
+	}
+
+	return f
+}
+
+func parseIDParam(v *validation.Validator, query url.Values, name string) sql.NullInt64 {
+	raw := query.Get(name)
+	if raw == "" {
+		return sql.NullInt64{}
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	v.Check(err == nil, name, "must be an integer")
+	return sql.NullInt64{Int64: id, Valid: err == nil}
+}
+
+// parseTimeRange reads <prefix>_from (inclusive) and <prefix>_to (exclusive;
+// a bare date covers the whole day).
+func parseTimeRange(v *validation.Validator, query url.Values, prefix string) (sql.NullTime, sql.NullTime) {
+	var from, to sql.NullTime
+
+	if raw := query.Get(prefix + "_from"); raw != "" {
+		t, err := parseQueryTime(raw, false)
+		v.Check(err == nil, prefix+"_from", "must be a date (YYYY-MM-DD) or RFC3339 timestamp")
+		from = sql.NullTime{Time: t.UTC(), Valid: err == nil}
+	}
+	if raw := query.Get(prefix + "_to"); raw != "" {
+		t, err := parseQueryTime(raw, true)
+		v.Check(err == nil, prefix+"_to", "must be a date (YYYY-MM-DD) or RFC3339 timestamp")
+		to = sql.NullTime{Time: t.UTC(), Valid: err == nil}
+	}
+
+	if from.Valid && to.Valid {
+		v.Check(from.Time.Before(to.Time), prefix+"_from", "must be before "+prefix+"_to")
+	}
+	return from, to
+}
+
+// searchTerms splits a search query into unique lowercase words; punctuation
+// never reaches the full-text engine.
+func searchTerms(raw string) []string {
+	words := strings.FieldsFunc(strings.ToLower(raw), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := map[string]bool{}
+	var terms []string
+	for _, w := range words {
+		if !seen[w] {
+			seen[w] = true
+			terms = append(terms, w)
+		}
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+func parseTaskSort(v *validation.Validator, query url.Values) db.TaskSort {
+	s := db.TaskSort{Field: db.TaskSortCreatedAt, Desc: true}
+
+	if raw := query.Get("sort"); raw != "" {
+		v.OneOf("sort", raw, taskSortFields...)
+		s.Field = db.TaskSortField(raw)
+	}
+	if raw := query.Get("order"); raw != "" {
+		v.OneOf("order", raw, "asc", "desc")
+		s.Desc = raw == "desc"
+	}
+
+	return s
+}
+
+// taskListVariant derives the cache key suffix from the normalized query, so
+// parameter order, repeated statuses or letter case do not split the cache.
+func taskListVariant(filter db.TaskFilter, s db.TaskSort, page pageParams, cursor string) string {
+	data, _ := json.Marshal(struct {
+		Filter       db.TaskFilter
+		Sort         db.TaskSort
+		Size         int
+		Cursor       string
+		IncludeTotal bool
+	}{filter, s, page.Size, cursor, page.IncludeTotal})
+
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
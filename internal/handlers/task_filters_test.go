@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/egor_lukyanovich/moon_test_application/pkg/validation"
+)
+
+func TestParseTaskFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		fields []string
+	}{
+		{name: "all filters", query: "status=done,todo&created_by=2&unassigned=true&created_from=2024-01-01&created_to=2024-02-01&updated_from=2024-01-10T00:00:00Z&q=deploy&sort=title&order=asc"},
+		{name: "custom status", query: "status=todo,review"},
+		{name: "malformed status", query: "status=todo,In%20Review", fields: []string{"status"}},
+		{name: "unassigned with assignee", query: "assignee_id=3&unassigned=true", fields: []string{"unassigned"}},
+		{name: "inverted range", query: "updated_from=2024-02-01&updated_to=2024-01-01", fields: []string{"updated_from"}},
+		{name: "bad dates", query: "created_from=yesterday&created_to=soon", fields: []string{"created_from", "created_to"}},
+		{name: "punctuation only search", query: "q=!!!", fields: []string{"q"}},
+		{name: "bad sort", query: "sort=priority&order=up", fields: []string{"sort", "order"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+			v := validation.New()
+			parseTaskFilter(v, query)
+			parseTaskSort(v, query)
+
+			if len(v.Errors()) != len(tt.fields) {
+				t.Fatalf("expected errors for %v, got %+v", tt.fields, v.Errors())
+			}
+			for i, field := range tt.fields {
+				if v.Errors()[i].Field != field {
+					t.Errorf("expected error for %s, got %+v", field, v.Errors()[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTaskListVariantIsNormalized(t *testing.T) {
+	variant := func(raw string) string {
+		query, _ := url.ParseQuery(raw)
+		v := validation.New()
+		filter := parseTaskFilter(v, query)
+		filter.TeamIDs = []int64{1}
+		sort := parseTaskSort(v, query)
+		page := parsePageParams(v, query, sort)
+		if !v.Valid() {
+			t.Fatalf("unexpected errors for %q: %+v", raw, v.Errors())
+		}
+		return taskListVariant(filter, sort, page, query.Get("cursor"))
+	}
+
+	same := variant("status=done&status=todo&q=Deploy+API&created_from=2024-01-01T03:00:00%2B03:00")
+	if other := variant("q=api+deploy+API&status=todo,done,todo&created_from=2024-01-01"); other != same {
+		t.Errorf("expected equivalent queries to share a cache variant")
+	}
+	if other := variant("status=done&status=todo&q=Deploy+API&created_from=2024-01-01&sort=title"); other == same {
+		t.Errorf("expected a different sort to change the cache variant")
+	}
+
+	query, _ := url.ParseQuery("status=done,todo")
+	filter := parseTaskFilter(validation.New(), query)
+	if len(filter.Statuses) != 2 || filter.Statuses[0] != "todo" {
+		t.Errorf("expected statuses in workflow order, got %v", filter.Statuses)
+	}
+}
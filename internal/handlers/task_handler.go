@@ -3,14 +3,13 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/egor_lukyanovich/moon_test_application/internal/db"
 	json_resp "github.com/egor_lukyanovich/moon_test_application/pkg/json"
 	id_helper "github.com/egor_lukyanovich/moon_test_application/pkg/routing"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/validation"
 	"github.com/go-chi/chi/v5"
 	"github.com/redis/go-redis/v9"
 )
@@ -18,11 +17,11 @@ import (
 type TaskHandlers struct {
 	q     *db.Queries
 	db    *sql.DB
-	redis *redis.Client
+	cache taskListCache
 }
 
 func NewTaskHandlers(q *db.Queries, database *sql.DB, redisClient *redis.Client) *TaskHandlers {
-	return &TaskHandlers{q: q, db: database, redis: redisClient}
+	return &TaskHandlers{q: q, db: database, cache: taskListCache{redis: redisClient}}
 }
 
 func (h *TaskHandlers) CreateTask(w http.ResponseWriter, r *http.Request) {
@@ -37,23 +36,76 @@ func (h *TaskHandlers) CreateTask(w http.ResponseWriter, r *http.Request) {
 		Description string `json:"description"`
 		Status      string `json:"status"`
 		TeamID      int64  `json:"team_id"`
+		AssigneeID  *int64 `json:"assignee_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		json_resp.RespondError(w, 400, "BAD_REQUEST", "invalid json")
 		return
 	}
 
+	v := validation.New()
+	validateTitle(v, req.Title)
+	validateDescription(v, req.Description)
+	validateStatus(v, req.Status)
+	v.Positive("team_id", req.TeamID)
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
 	if !id_helper.CheckTeamRole(r.Context(), h.q, req.TeamID, userID) {
 		json_resp.RespondError(w, 403, "FORBIDDEN", "you are not a member of this team")
 		return
 	}
 
-	res, err := h.q.CreateTask(r.Context(), db.CreateTaskParams{
+	var assignee sql.NullInt64
+	if req.AssigneeID != nil {
+		assignee = sql.NullInt64{Int64: *req.AssigneeID, Valid: true}
+	}
+	validateAssignee(r.Context(), v, h.q, req.TeamID, assignee)
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "tx failed")
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.q.WithTx(tx)
+
+	if _, err := qtx.LockTeam(r.Context(), req.TeamID); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to lock team")
+		return
+	}
+
+	wf, err := loadWorkflow(r.Context(), qtx, req.TeamID)
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to fetch workflow")
+		return
+	}
+	wf.checkStatusChange(v, "", req.Status)
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
+	last, err := qtx.LastTaskPosition(r.Context(), db.LastTaskPositionParams{TeamID: req.TeamID, Status: req.Status})
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to fetch task position")
+		return
+	}
+
+	res, err := qtx.CreateTask(r.Context(), db.CreateTaskParams{
 		Title:       req.Title,
 		Description: sql.NullString{String: req.Description, Valid: req.Description != ""},
-		Status:      db.TasksStatus(req.Status),
+		Status:      req.Status,
 		TeamID:      req.TeamID,
+		AssigneeID:  assignee,
 		CreatedBy:   userID,
+		Position:    last + taskPositionStep,
 	})
 	if err != nil {
 		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to create task")
@@ -61,72 +113,157 @@ func (h *TaskHandlers) CreateTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	taskID, _ := res.LastInsertId()
+
+	err = qtx.CreateTaskHistory(r.Context(), db.CreateTaskHistoryParams{
+		TaskID:     taskID,
+		ChangedBy:  sql.NullInt64{Int64: userID, Valid: true},
+		ChangeType: "created",
+		NewValue:   sql.NullString{String: req.Title, Valid: true},
+	})
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to write task history")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to commit tx")
+		return
+	}
+
+	h.cache.Invalidate(r.Context(), req.TeamID)
+
 	json_resp.RespondJSON(w, 201, map[string]interface{}{"task_id": taskID})
 }
 
 func (h *TaskHandlers) ListTasks(w http.ResponseWriter, r *http.Request) {
-	teamID, _ := strconv.ParseInt(r.URL.Query().Get("team_id"), 10, 64)
-	if teamID == 0 {
-		json_resp.RespondError(w, 400, "BAD_REQUEST", "team_id is required")
+	userID, ok := id_helper.GetUserIDHelper(r.Context())
+	if !ok {
+		json_resp.RespondError(w, 401, "UNAUTHORIZED", "unauthorized")
 		return
 	}
 
-	status := r.URL.Query().Get("status")
-	assigneeStr := r.URL.Query().Get("assignee_id")
+	query := r.URL.Query()
+	teamID, teamErr := strconv.ParseInt(query.Get("team_id"), 10, 64)
 
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page < 1 {
-		page = 1
+	v := validation.New()
+	v.Check(teamErr == nil && teamID > 0, "team_id", "is required and must be a positive integer")
+	filter := parseTaskFilter(v, query)
+	filter.TeamIDs = []int64{teamID}
+	sort := parseTaskSort(v, query)
+	page := parsePageParams(v, query, sort)
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
 	}
-	limit := 10
-	offset := (page - 1) * limit
 
-	cacheKey := fmt.Sprintf("tasks:t:%d:s:%s:a:%s:p:%d", teamID, status, assigneeStr, page)
-	cachedData, err := h.redis.Get(r.Context(), cacheKey).Result()
-	if err == nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(cachedData))
+	// Cached pages are shared by all members of the team, so membership must be
+	// checked before the cache is consulted, not only on a miss.
+	if !id_helper.CheckTeamRole(r.Context(), h.q, teamID, userID) {
+		json_resp.RespondError(w, 403, "FORBIDDEN", "you are not a member of this team")
 		return
 	}
 
-	statusNull := db.NullTasksStatus{}
-	if status != "" {
-		statusNull = db.NullTasksStatus{
-			TasksStatus: db.TasksStatus(status),
-			Valid:       true,
+	cacheKey, cacheErr := h.cache.key(r.Context(), teamID, taskListVariant(filter, sort, page, query.Get("cursor")))
+	if cacheErr == nil {
+		if cachedData, hit := h.cache.Get(r.Context(), cacheKey); hit {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(cachedData)
+			return
 		}
 	}
 
-	var assigneeNull sql.NullInt64
-	if assigneeStr != "" {
-		aID, _ := strconv.ParseInt(assigneeStr, 10, 64)
-		assigneeNull = sql.NullInt64{Int64: aID, Valid: true}
+	response, ok := h.taskPage(w, r, filter, sort, page)
+	if !ok {
+		return
 	}
 
-	tasks, err := h.q.ListTasks(r.Context(), db.ListTasksParams{
-		TeamID:     teamID,
-		Status:     statusNull,
-		AssigneeID: assigneeNull,
-		Limit:      int32(limit),
-		Offset:     int32(offset),
-	})
+	if cacheErr == nil {
+		dataToCache, _ := json.Marshal(response)
+		h.cache.Set(r.Context(), cacheKey, dataToCache)
+	}
+
+	json_resp.RespondJSON(w, 200, response)
+}
+
+// MyTasks lists tasks assigned to or created by the caller across all of
+// their teams. Results span several teams, so they bypass the per-team cache.
+func (h *TaskHandlers) MyTasks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := id_helper.GetUserIDHelper(r.Context())
+	if !ok {
+		json_resp.RespondError(w, 401, "UNAUTHORIZED", "unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	v := validation.New()
+	teamID := parseIDParam(v, query, "team_id")
+	filter := parseTaskFilter(v, query)
+	filter.InvolvedUserID = sql.NullInt64{Int64: userID, Valid: true}
+	sort := parseTaskSort(v, query)
+	page := parsePageParams(v, query, sort)
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
+	teams, err := h.q.ListUserTeams(r.Context(), userID)
 	if err != nil {
-		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to fetch tasks")
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to fetch teams")
 		return
 	}
-	if tasks == nil {
-		tasks = []db.Task{}
+	for _, team := range teams {
+		if !teamID.Valid || team.ID == teamID.Int64 {
+			filter.TeamIDs = append(filter.TeamIDs, team.ID)
+		}
 	}
 
-	dataToCache, _ := json.Marshal(tasks)
-	h.redis.Set(r.Context(), cacheKey, dataToCache, 5*time.Minute)
+	if len(filter.TeamIDs) == 0 {
+		if teamID.Valid {
+			json_resp.RespondError(w, 403, "FORBIDDEN", "you are not a member of this team")
+			return
+		}
+		json_resp.RespondJSON(w, 200, newTaskPage(nil, page.Size, sort))
+		return
+	}
+
+	response, ok := h.taskPage(w, r, filter, sort, page)
+	if !ok {
+		return
+	}
 
-	json_resp.RespondJSON(w, 200, tasks)
+	json_resp.RespondJSON(w, 200, response)
+}
+
+func (h *TaskHandlers) taskPage(w http.ResponseWriter, r *http.Request, filter db.TaskFilter, sort db.TaskSort, page pageParams) (taskPage, bool) {
+	tasks, err := h.q.ListFilteredTasks(r.Context(), db.ListFilteredTasksParams{
+		Filter: filter,
+		Sort:   sort,
+		After:  page.Cursor,
+		Limit:  int32(page.Size + 1),
+	})
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to fetch tasks")
+		return taskPage{}, false
+	}
+
+	response := newTaskPage(tasks, page.Size, sort)
+
+	if page.IncludeTotal {
+		total, err := h.q.CountFilteredTasks(r.Context(), filter)
+		if err != nil {
+			json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to count tasks")
+			return taskPage{}, false
+		}
+		response.Total = &total
+	}
+
+	return response, true
 }
 
 func (h *TaskHandlers) UpdateTask(w http.ResponseWriter, r *http.Request) {
 	userID, ok := id_helper.GetUserIDHelper(r.Context())
 	if !ok {
+		json_resp.RespondError(w, 401, "UNAUTHORIZED", "unauthorized")
 		return
 	}
 
@@ -137,15 +274,27 @@ func (h *TaskHandlers) UpdateTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Title      string `json:"title"`
-		Status     string `json:"status"`
-		AssigneeID *int64 `json:"assignee_id"`
+		Title       string  `json:"title"`
+		Description *string `json:"description"`
+		Status      string  `json:"status"`
+		AssigneeID  *int64  `json:"assignee_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		json_resp.RespondError(w, 400, "BAD_REQUEST", "invalid json")
 		return
 	}
 
+	v := validation.New()
+	validateTitle(v, req.Title)
+	validateStatus(v, req.Status)
+	if req.Description != nil {
+		validateDescription(v, *req.Description)
+	}
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
 	tx, err := h.db.BeginTx(r.Context(), nil)
 	if err != nil {
 		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "tx failed")
@@ -154,9 +303,8 @@ func (h *TaskHandlers) UpdateTask(w http.ResponseWriter, r *http.Request) {
 	defer tx.Rollback()
 	qtx := h.q.WithTx(tx)
 
-	oldTask, err := qtx.GetTaskByID(r.Context(), taskID)
-	if err != nil {
-		json_resp.RespondError(w, 404, "NOT_FOUND", "task not found")
+	oldTask, ok := h.lockTask(w, r, qtx, taskID)
+	if !ok {
 		return
 	}
 
@@ -165,32 +313,342 @@ func (h *TaskHandlers) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var newAssignee sql.NullInt64
+	newTask := oldTask
+	newTask.Title = req.Title
+	newTask.Status = req.Status
+	newTask.Description = sql.NullString{}
+	if req.Description != nil {
+		newTask.Description = sql.NullString{String: *req.Description, Valid: true}
+	}
+	newTask.AssigneeID = sql.NullInt64{}
 	if req.AssigneeID != nil {
-		newAssignee = sql.NullInt64{Int64: *req.AssigneeID, Valid: true}
+		newTask.AssigneeID = sql.NullInt64{Int64: *req.AssigneeID, Valid: true}
+	}
+
+	validateAssignee(r.Context(), v, qtx, oldTask.TeamID, newTask.AssigneeID)
+	if !h.checkStatusChange(w, r, v, qtx, oldTask, newTask) {
+		return
 	}
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
 	err = qtx.UpdateTask(r.Context(), db.UpdateTaskParams{
 		ID:          taskID,
-		Title:       req.Title,
-		Status:      db.TasksStatus(req.Status),
-		AssigneeID:  newAssignee,
-		Description: oldTask.Description,
+		Title:       newTask.Title,
+		Status:      newTask.Status,
+		AssigneeID:  newTask.AssigneeID,
+		Description: newTask.Description,
 	})
 	if err != nil {
 		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to update task")
 		return
 	}
 
-	if string(oldTask.Status) != req.Status {
-		_ = qtx.CreateTaskHistory(r.Context(), db.CreateTaskHistoryParams{
-			TaskID:     taskID,
-			ChangedBy:  sql.NullInt64{Int64: userID, Valid: true},
-			ChangeType: "status_update",
-			OldValue:   sql.NullString{String: string(oldTask.Status), Valid: true},
-			NewValue:   sql.NullString{String: req.Status, Valid: true},
-		})
+	if newTask.Status != oldTask.Status {
+		if err := appendToColumn(r.Context(), qtx, newTask); err != nil {
+			json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to update task position")
+			return
+		}
+	}
+
+	if err := recordTaskChanges(r.Context(), qtx, userID, oldTask, newTask); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to write task history")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to commit tx")
+		return
+	}
+
+	h.cache.Invalidate(r.Context(), oldTask.TeamID)
+
+	json_resp.RespondJSON(w, 200, map[string]string{"status": "updated"})
+}
+
+func (h *TaskHandlers) PatchTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := id_helper.GetUserIDHelper(r.Context())
+	if !ok {
+		json_resp.RespondError(w, 401, "UNAUTHORIZED", "unauthorized")
+		return
+	}
+
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		json_resp.RespondError(w, 400, "BAD_REQUEST", "invalid task id")
+		return
+	}
+
+	var req struct {
+		Title       optional[string] `json:"title"`
+		Description optional[string] `json:"description"`
+		Status      optional[string] `json:"status"`
+		AssigneeID  optional[int64]  `json:"assignee_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json_resp.RespondError(w, 400, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	v := validation.New()
+	if req.Title.Set {
+		v.Check(req.Title.Value != nil, "title", "cannot be null")
+		if req.Title.Value != nil {
+			validateTitle(v, *req.Title.Value)
+		}
+	}
+	if req.Status.Set {
+		v.Check(req.Status.Value != nil, "status", "cannot be null")
+		if req.Status.Value != nil {
+			validateStatus(v, *req.Status.Value)
+		}
+	}
+	if req.Description.Set && req.Description.Value != nil {
+		validateDescription(v, *req.Description.Value)
+	}
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "tx failed")
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.q.WithTx(tx)
+
+	oldTask, ok := h.lockTask(w, r, qtx, taskID)
+	if !ok {
+		return
+	}
+
+	if !id_helper.CheckTeamRole(r.Context(), qtx, oldTask.TeamID, userID) {
+		json_resp.RespondError(w, 403, "FORBIDDEN", "access denied")
+		return
+	}
+
+	params := db.PatchTaskParams{
+		ID:             taskID,
+		SetDescription: req.Description.Set,
+		SetAssignee:    req.AssigneeID.Set,
+	}
+	newTask := oldTask
+
+	if req.Title.Set {
+		params.Title = sql.NullString{String: *req.Title.Value, Valid: true}
+		newTask.Title = *req.Title.Value
+	}
+	if req.Description.Set {
+		if req.Description.Value != nil {
+			params.Description = sql.NullString{String: *req.Description.Value, Valid: true}
+		}
+		newTask.Description = params.Description
+	}
+	if req.Status.Set {
+		params.Status = sql.NullString{String: *req.Status.Value, Valid: true}
+		newTask.Status = params.Status.String
+	}
+	if req.AssigneeID.Set {
+		if req.AssigneeID.Value != nil {
+			params.AssigneeID = sql.NullInt64{Int64: *req.AssigneeID.Value, Valid: true}
+		}
+		newTask.AssigneeID = params.AssigneeID
+		validateAssignee(r.Context(), v, qtx, oldTask.TeamID, newTask.AssigneeID)
+	}
+	if !h.checkStatusChange(w, r, v, qtx, oldTask, newTask) {
+		return
+	}
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
+	if err := qtx.PatchTask(r.Context(), params); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to update task")
+		return
+	}
+
+	if newTask.Status != oldTask.Status {
+		if err := appendToColumn(r.Context(), qtx, newTask); err != nil {
+			json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to update task position")
+			return
+		}
+	}
+
+	if err := recordTaskChanges(r.Context(), qtx, userID, oldTask, newTask); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to write task history")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to commit tx")
+		return
 	}
 
-	tx.Commit()
+	h.cache.Invalidate(r.Context(), oldTask.TeamID)
+
 	json_resp.RespondJSON(w, 200, map[string]string{"status": "updated"})
 }
+
+func (h *TaskHandlers) DeleteTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := id_helper.GetUserIDHelper(r.Context())
+	if !ok {
+		json_resp.RespondError(w, 401, "UNAUTHORIZED", "unauthorized")
+		return
+	}
+
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		json_resp.RespondError(w, 400, "BAD_REQUEST", "invalid task id")
+		return
+	}
+
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "tx failed")
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.q.WithTx(tx)
+
+	task, err := qtx.GetTaskByID(r.Context(), taskID)
+	if err != nil {
+		json_resp.RespondError(w, 404, "NOT_FOUND", "task not found")
+		return
+	}
+
+	isCreator := task.CreatedBy == userID && id_helper.CheckTeamRole(r.Context(), qtx, task.TeamID, userID)
+	if !isCreator && !id_helper.CheckTeamRole(r.Context(), qtx, task.TeamID, userID, "owner", "admin") {
+		json_resp.RespondError(w, 403, "FORBIDDEN", "only task creator or team owner/admin can delete the task")
+		return
+	}
+
+	err = qtx.CreateTaskHistory(r.Context(), db.CreateTaskHistoryParams{
+		TaskID:     taskID,
+		ChangedBy:  sql.NullInt64{Int64: userID, Valid: true},
+		ChangeType: "deleted",
+		OldValue:   sql.NullString{String: task.Title, Valid: true},
+	})
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to write task history")
+		return
+	}
+
+	if err := qtx.DeleteTask(r.Context(), taskID); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to delete task")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to commit tx")
+		return
+	}
+
+	h.cache.Invalidate(r.Context(), task.TeamID)
+
+	json_resp.RespondJSON(w, 200, map[string]string{"status": "deleted"})
+}
+
+func (h *TaskHandlers) RepairInvalidTasks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := id_helper.GetUserIDHelper(r.Context())
+	if !ok {
+		json_resp.RespondError(w, 401, "UNAUTHORIZED", "unauthorized")
+		return
+	}
+
+	teamID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		json_resp.RespondError(w, 400, "BAD_REQUEST", "invalid team id")
+		return
+	}
+
+	if !id_helper.CheckTeamRole(r.Context(), h.q, teamID, userID, "owner") {
+		json_resp.RespondError(w, 403, "FORBIDDEN", "only team owner can repair tasks")
+		return
+	}
+
+	var req struct {
+		Strategy   string `json:"strategy"`
+		AssigneeID int64  `json:"assignee_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json_resp.RespondError(w, 400, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	v := validation.New()
+	v.OneOf("strategy", req.Strategy, "unassign", "reassign")
+	var newAssignee sql.NullInt64
+	if req.Strategy == "reassign" {
+		v.Positive("assignee_id", req.AssigneeID)
+		newAssignee = sql.NullInt64{Int64: req.AssigneeID, Valid: true}
+		validateAssignee(r.Context(), v, h.q, teamID, newAssignee)
+	}
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "tx failed")
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.q.WithTx(tx)
+
+	invalidTasks, err := qtx.FindInvalidTasks(r.Context(), db.FindInvalidTasksParams{
+		TeamID: sql.NullInt64{Int64: teamID, Valid: true},
+	})
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to fetch invalid tasks")
+		return
+	}
+
+	repaired := []int64{}
+	for _, invalid := range invalidTasks {
+		oldTask, err := qtx.GetTaskByID(r.Context(), invalid.ID)
+		if err != nil {
+			json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to fetch task")
+			return
+		}
+
+		newTask := oldTask
+		newTask.AssigneeID = newAssignee
+
+		err = qtx.PatchTask(r.Context(), db.PatchTaskParams{
+			ID:          oldTask.ID,
+			SetAssignee: true,
+			AssigneeID:  newAssignee,
+		})
+		if err != nil {
+			json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to update task")
+			return
+		}
+
+		if err := recordTaskChanges(r.Context(), qtx, userID, oldTask, newTask); err != nil {
+			json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to write task history")
+			return
+		}
+
+		repaired = append(repaired, oldTask.ID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to commit tx")
+		return
+	}
+
+	if len(repaired) > 0 {
+		h.cache.Invalidate(r.Context(), teamID)
+	}
+
+	json_resp.RespondJSON(w, 200, map[string]interface{}{
+		"repaired": len(repaired),
+		"task_ids": repaired,
+	})
+}
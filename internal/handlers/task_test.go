@@ -12,6 +12,7 @@ import (
 	"testing"
 
 	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+	models "github.com/egor_lukyanovich/moon_test_application/internal/models"
 	id_helper "github.com/egor_lukyanovich/moon_test_application/pkg/routing"
 	"github.com/go-chi/chi/v5"
 	_ "github.com/go-sql-driver/mysql"
@@ -55,7 +56,9 @@ func setupTestDBWithTasks(t *testing.T) (*sql.DB, func()) {
 		id BIGINT AUTO_INCREMENT PRIMARY KEY,
 		email VARCHAR(255) NOT NULL UNIQUE,
 		password_hash VARCHAR(255) NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+		display_name VARCHAR(100)
 	);
 	CREATE TABLE teams (
 		id BIGINT AUTO_INCREMENT PRIMARY KEY,
@@ -74,21 +77,54 @@ func setupTestDBWithTasks(t *testing.T) (*sql.DB, func()) {
 		id BIGINT AUTO_INCREMENT PRIMARY KEY,
 		title VARCHAR(255) NOT NULL,
 		description TEXT,
-		status ENUM('todo', 'in_progress', 'done') NOT NULL DEFAULT 'todo',
+		status VARCHAR(50) NOT NULL DEFAULT 'todo',
 		team_id BIGINT NOT NULL,
 		assignee_id BIGINT,
 		created_by BIGINT NOT NULL,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		position BIGINT NOT NULL DEFAULT 0,
+		FULLTEXT INDEX idx_tasks_fulltext (title, description)
+	);
+	CREATE TABLE team_statuses (
+		team_id BIGINT NOT NULL,
+		name VARCHAR(50) NOT NULL,
+		position INT NOT NULL,
+		PRIMARY KEY (team_id, name)
+	);
+	CREATE TABLE team_status_transitions (
+		team_id BIGINT NOT NULL,
+		from_status VARCHAR(50) NOT NULL,
+		to_status VARCHAR(50) NOT NULL,
+		PRIMARY KEY (team_id, from_status, to_status)
 	);
 	CREATE TABLE task_history (
 		id BIGINT AUTO_INCREMENT PRIMARY KEY,
 		task_id BIGINT NOT NULL,
 		changed_by BIGINT,
 		change_type VARCHAR(50) NOT NULL,
-		old_value VARCHAR(255),
-		new_value VARCHAR(255),
+		old_value TEXT,
+		new_value TEXT,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE task_comments (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		task_id BIGINT NOT NULL,
+		user_id BIGINT NOT NULL,
+		content TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE team_invitations (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		team_id BIGINT NOT NULL,
+		email VARCHAR(255) NOT NULL,
+		user_id BIGINT NULL,
+		role ENUM('admin', 'member') NOT NULL DEFAULT 'member',
+		status ENUM('pending', 'accepted', 'declined') NOT NULL DEFAULT 'pending',
+		invited_by BIGINT NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		responded_at TIMESTAMP NULL
 	);`
 
 	_, err = database.Exec(schema)
@@ -129,6 +165,15 @@ func setupTestRedis(t *testing.T) (*goredis.Client, func()) {
 	return client, cleanup
 }
 
+// listTasksAs calls ListTasks on behalf of userID.
+func listTasksAs(h *TaskHandlers, userID int64, url string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req = req.WithContext(id_helper.WithUserID(req.Context(), userID))
+	rr := httptest.NewRecorder()
+	h.ListTasks(rr, req)
+	return rr
+}
+
 func TestCreateTask(t *testing.T) {
 	database, cleanupDB := setupTestDBWithTasks(t)
 	defer cleanupDB()
@@ -162,6 +207,29 @@ func TestCreateTask(t *testing.T) {
 	if rr.Code != http.StatusCreated {
 		t.Errorf("expected status %v; got %v. Body: %s", http.StatusCreated, rr.Code, rr.Body.String())
 	}
+
+	var count int
+	err := database.QueryRow("SELECT COUNT(*) FROM task_history WHERE change_type = 'created'").Scan(&count)
+	if err != nil || count != 1 {
+		t.Errorf("expected created entry in task history, count: %v", count)
+	}
+
+	badBody := []byte(`{"title": "", "status": "Archived", "team_id": ` + strconv.FormatInt(teamID, 10) + `}`)
+	badReq := httptest.NewRequest(http.MethodPost, "/tasks", bytes.NewBuffer(badBody))
+	badReq = badReq.WithContext(id_helper.WithUserID(badReq.Context(), userID))
+
+	rrBad := httptest.NewRecorder()
+	taskHandlers.CreateTask(rrBad, badReq)
+
+	if rrBad.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %v; got %v", http.StatusUnprocessableEntity, rrBad.Code)
+	}
+
+	var errResp models.ErrorResponse
+	json.NewDecoder(rrBad.Body).Decode(&errResp)
+	if len(errResp.Error.Details) != 2 {
+		t.Errorf("expected title and status field errors, got %+v", errResp.Error.Details)
+	}
 }
 
 func TestListTasksAndCache(t *testing.T) {
@@ -183,15 +251,22 @@ func TestListTasksAndCache(t *testing.T) {
 	})
 	teamID, _ := resTeam.LastInsertId()
 
+	_ = queries.AddTeamMember(context.Background(), db.AddTeamMemberParams{
+		TeamID: teamID, UserID: userID, Role: "owner",
+	})
+
+	resOutsider, _ := queries.CreateUser(context.Background(), db.CreateUserParams{
+		Email: "list_outsider@example.com", PasswordHash: "hash",
+	})
+	outsiderID, _ := resOutsider.LastInsertId()
+
 	_, _ = queries.CreateTask(context.Background(), db.CreateTaskParams{
 		Title: "Cache me", Status: "todo", TeamID: teamID, CreatedBy: userID,
 	})
 
 	url := "/tasks?team_id=" + strconv.FormatInt(teamID, 10) + "&status=todo"
-	req := httptest.NewRequest(http.MethodGet, url, nil)
 
-	rr1 := httptest.NewRecorder()
-	taskHandlers.ListTasks(rr1, req)
+	rr1 := listTasksAs(taskHandlers, userID, url)
 
 	if rr1.Code != http.StatusOK {
 		t.Errorf("expected 200, got %v", rr1.Code)
@@ -204,14 +279,18 @@ func TestListTasksAndCache(t *testing.T) {
 
 	_, _ = database.Exec("DELETE FROM tasks")
 
-	rr2 := httptest.NewRecorder()
-	taskHandlers.ListTasks(rr2, req)
+	rr2 := listTasksAs(taskHandlers, userID, url)
 
-	var response []map[string]interface{}
+	var response taskPage
 	json.NewDecoder(rr2.Body).Decode(&response)
-	if len(response) == 0 {
+	if len(response.Items) == 0 {
 		t.Errorf("expected data to be returned from redis cache, but got empty array")
 	}
+
+	// The page is cached now; an outsider asking for the same URL must still be refused.
+	if rr := listTasksAs(taskHandlers, outsiderID, url); rr.Code != http.StatusForbidden {
+		t.Errorf("expected outsider to get 403 despite the cached page, got %d: %s", rr.Code, rr.Body.String())
+	}
 }
 
 func TestUpdateTaskHistory(t *testing.T) {
@@ -262,4 +341,816 @@ func TestUpdateTaskHistory(t *testing.T) {
 	if err != nil || count == 0 {
 		t.Errorf("expected task history to be created, count: %v", count)
 	}
+
+	rows, err := database.Query("SELECT change_type FROM task_history WHERE task_id = ? ORDER BY change_type", taskID)
+	if err != nil {
+		t.Fatalf("failed to read task history: %v", err)
+	}
+	defer rows.Close()
+
+	var changeTypes []string
+	for rows.Next() {
+		var changeType string
+		rows.Scan(&changeType)
+		changeTypes = append(changeTypes, changeType)
+	}
+	if strings.Join(changeTypes, ",") != "status_update,title_update" {
+		t.Errorf("expected title and status changes to be recorded, got %v", changeTypes)
+	}
+}
+
+func TestDeleteTask(t *testing.T) {
+	database, cleanupDB := setupTestDBWithTasks(t)
+	defer cleanupDB()
+	rdb, cleanupRedis := setupTestRedis(t)
+	defer cleanupRedis()
+
+	queries := db.New(database)
+	taskHandlers := NewTaskHandlers(queries, database, rdb)
+
+	resOwner, _ := queries.CreateUser(context.Background(), db.CreateUserParams{
+		Email: "delete_owner@example.com", PasswordHash: "hash",
+	})
+	ownerID, _ := resOwner.LastInsertId()
+
+	resMember, _ := queries.CreateUser(context.Background(), db.CreateUserParams{
+		Email: "delete_member@example.com", PasswordHash: "hash",
+	})
+	memberID, _ := resMember.LastInsertId()
+
+	resTeam, _ := queries.CreateTeam(context.Background(), db.CreateTeamParams{
+		Name: "Delete Team", CreatedBy: ownerID,
+	})
+	teamID, _ := resTeam.LastInsertId()
+
+	_ = queries.AddTeamMember(context.Background(), db.AddTeamMemberParams{
+		TeamID: teamID, UserID: ownerID, Role: "owner",
+	})
+	_ = queries.AddTeamMember(context.Background(), db.AddTeamMemberParams{
+		TeamID: teamID, UserID: memberID, Role: "member",
+	})
+
+	resTask, _ := queries.CreateTask(context.Background(), db.CreateTaskParams{
+		Title: "Delete me", Status: "todo", TeamID: teamID, CreatedBy: ownerID,
+	})
+	taskID, _ := resTask.LastInsertId()
+
+	listURL := "/tasks?team_id=" + strconv.FormatInt(teamID, 10)
+	listTasksAs(taskHandlers, memberID, listURL)
+
+	r := chi.NewRouter()
+	r.Delete("/tasks/{id}", taskHandlers.DeleteTask)
+	url := "/tasks/" + strconv.FormatInt(taskID, 10)
+
+	reqMember := httptest.NewRequest(http.MethodDelete, url, nil)
+	reqMember = reqMember.WithContext(id_helper.WithUserID(reqMember.Context(), memberID))
+	rrMember := httptest.NewRecorder()
+	r.ServeHTTP(rrMember, reqMember)
+
+	if rrMember.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for plain member, got %v", rrMember.Code)
+	}
+
+	reqOwner := httptest.NewRequest(http.MethodDelete, url, nil)
+	reqOwner = reqOwner.WithContext(id_helper.WithUserID(reqOwner.Context(), ownerID))
+	rrOwner := httptest.NewRecorder()
+	r.ServeHTTP(rrOwner, reqOwner)
+
+	if rrOwner.Code != http.StatusOK {
+		t.Errorf("expected 200, got %v. Body: %s", rrOwner.Code, rrOwner.Body.String())
+	}
+
+	if _, err := queries.GetTaskByID(context.Background(), taskID); err == nil {
+		t.Errorf("expected task to be deleted")
+	}
+
+	var count int
+	err := database.QueryRow("SELECT COUNT(*) FROM task_history WHERE task_id = ? AND change_type = 'deleted'", taskID).Scan(&count)
+	if err != nil || count != 1 {
+		t.Errorf("expected deletion to be recorded in task history, count: %v", count)
+	}
+
+	rrList := listTasksAs(taskHandlers, memberID, listURL)
+
+	var response taskPage
+	json.NewDecoder(rrList.Body).Decode(&response)
+	if len(response.Items) != 0 {
+		t.Errorf("expected cached task list of the team to be invalidated, got %d tasks", len(response.Items))
+	}
+}
+
+func TestCreateTaskInvalidatesListCache(t *testing.T) {
+	database, cleanupDB := setupTestDBWithTasks(t)
+	defer cleanupDB()
+	rdb, cleanupRedis := setupTestRedis(t)
+	defer cleanupRedis()
+
+	queries := db.New(database)
+	taskHandlers := NewTaskHandlers(queries, database, rdb)
+
+	resUser, _ := queries.CreateUser(context.Background(), db.CreateUserParams{
+		Email: "cache_writer@example.com", PasswordHash: "hash",
+	})
+	userID, _ := resUser.LastInsertId()
+
+	resTeam, _ := queries.CreateTeam(context.Background(), db.CreateTeamParams{
+		Name: "Cache Team", CreatedBy: userID,
+	})
+	teamID, _ := resTeam.LastInsertId()
+
+	_ = queries.AddTeamMember(context.Background(), db.AddTeamMemberParams{
+		TeamID: teamID, UserID: userID, Role: "owner",
+	})
+
+	listURL := "/tasks?team_id=" + strconv.FormatInt(teamID, 10)
+	for _, url := range []string{listURL, listURL + "&status=todo", listURL + "&page_size=1"} {
+		listTasksAs(taskHandlers, userID, url)
+	}
+
+	reqBody := []byte(`{"title": "Fresh Task", "status": "todo", "team_id": ` + strconv.FormatInt(teamID, 10) + `}`)
+	req := httptest.NewRequest(http.MethodPost, "/tasks", bytes.NewBuffer(reqBody))
+	req = req.WithContext(id_helper.WithUserID(req.Context(), userID))
+	taskHandlers.CreateTask(httptest.NewRecorder(), req)
+
+	for _, url := range []string{listURL, listURL + "&status=todo"} {
+		rr := listTasksAs(taskHandlers, userID, url)
+
+		var response taskPage
+		json.NewDecoder(rr.Body).Decode(&response)
+		if len(response.Items) != 1 {
+			t.Errorf("expected %s to reflect the new task, got %d tasks", url, len(response.Items))
+		}
+	}
+}
+
+func TestPatchTask(t *testing.T) {
+	database, cleanupDB := setupTestDBWithTasks(t)
+	defer cleanupDB()
+	rdb, cleanupRedis := setupTestRedis(t)
+	defer cleanupRedis()
+
+	queries := db.New(database)
+	taskHandlers := NewTaskHandlers(queries, database, rdb)
+
+	resUser, _ := queries.CreateUser(context.Background(), db.CreateUserParams{
+		Email: "patcher@example.com", PasswordHash: "hash",
+	})
+	userID, _ := resUser.LastInsertId()
+
+	resTeam, _ := queries.CreateTeam(context.Background(), db.CreateTeamParams{
+		Name: "Patch Team", CreatedBy: userID,
+	})
+	teamID, _ := resTeam.LastInsertId()
+
+	_ = queries.AddTeamMember(context.Background(), db.AddTeamMemberParams{
+		TeamID: teamID, UserID: userID, Role: "member",
+	})
+
+	resTask, _ := queries.CreateTask(context.Background(), db.CreateTaskParams{
+		Title:      "Patch me",
+		Status:     "in_progress",
+		TeamID:     teamID,
+		AssigneeID: sql.NullInt64{Int64: userID, Valid: true},
+		CreatedBy:  userID,
+	})
+	taskID, _ := resTask.LastInsertId()
+
+	r := chi.NewRouter()
+	r.Put("/tasks/{id}", taskHandlers.UpdateTask)
+	r.Patch("/tasks/{id}", taskHandlers.PatchTask)
+	url := "/tasks/" + strconv.FormatInt(taskID, 10)
+
+	do := func(method string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req = req.WithContext(id_helper.WithUserID(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := do(http.MethodPatch, []byte(`{"description": "more details"}`)); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v. Body: %s", rr.Code, rr.Body.String())
+	}
+
+	task, _ := queries.GetTaskByID(context.Background(), taskID)
+	if task.Title != "Patch me" || task.Status != "in_progress" || !task.AssigneeID.Valid || task.Description.String != "more details" {
+		t.Errorf("expected only description to change, got %+v", task)
+	}
+
+	if rr := do(http.MethodPatch, []byte(`{"assignee_id": null}`)); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v. Body: %s", rr.Code, rr.Body.String())
+	}
+
+	task, _ = queries.GetTaskByID(context.Background(), taskID)
+	if task.AssigneeID.Valid || task.Description.String != "more details" {
+		t.Errorf("expected explicit null to unassign and keep description, got %+v", task)
+	}
+
+	if rr := do(http.MethodPut, []byte(`{"status": "done"}`)); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for PUT without title, got %v", rr.Code)
+	}
+
+	var count int
+	err := database.QueryRow("SELECT COUNT(*) FROM task_history WHERE task_id = ?", taskID).Scan(&count)
+	if err != nil || count != 2 {
+		t.Errorf("expected description and assignee changes in history, count: %v", count)
+	}
+}
+
+func TestAssigneeMustBeTeamMember(t *testing.T) {
+	database, cleanupDB := setupTestDBWithTasks(t)
+	defer cleanupDB()
+	rdb, cleanupRedis := setupTestRedis(t)
+	defer cleanupRedis()
+
+	queries := db.New(database)
+	taskHandlers := NewTaskHandlers(queries, database, rdb)
+
+	resOwner, _ := queries.CreateUser(context.Background(), db.CreateUserParams{
+		Email: "repair_owner@example.com", PasswordHash: "hash",
+	})
+	ownerID, _ := resOwner.LastInsertId()
+
+	resOutsider, _ := queries.CreateUser(context.Background(), db.CreateUserParams{
+		Email: "repair_outsider@example.com", PasswordHash: "hash",
+	})
+	outsiderID, _ := resOutsider.LastInsertId()
+
+	resTeam, _ := queries.CreateTeam(context.Background(), db.CreateTeamParams{
+		Name: "Repair Team", CreatedBy: ownerID,
+	})
+	teamID, _ := resTeam.LastInsertId()
+
+	_ = queries.AddTeamMember(context.Background(), db.AddTeamMemberParams{
+		TeamID: teamID, UserID: ownerID, Role: "owner",
+	})
+
+	r := chi.NewRouter()
+	r.Post("/tasks", taskHandlers.CreateTask)
+	r.Patch("/tasks/{id}", taskHandlers.PatchTask)
+	r.Post("/teams/{id}/invalid-tasks/repair", taskHandlers.RepairInvalidTasks)
+
+	do := func(method, url string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req = req.WithContext(id_helper.WithUserID(req.Context(), ownerID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	teamStr := strconv.FormatInt(teamID, 10)
+	outsiderStr := strconv.FormatInt(outsiderID, 10)
+
+	rrCreate := do(http.MethodPost, "/tasks", []byte(`{"title": "T", "status": "todo", "team_id": `+teamStr+`, "assignee_id": `+outsiderStr+`}`))
+	if rrCreate.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 when assigning a non-member on create, got %v", rrCreate.Code)
+	}
+
+	resTask, _ := queries.CreateTask(context.Background(), db.CreateTaskParams{
+		Title:      "Broken",
+		Status:     "todo",
+		TeamID:     teamID,
+		AssigneeID: sql.NullInt64{Int64: outsiderID, Valid: true},
+		CreatedBy:  ownerID,
+	})
+	taskID, _ := resTask.LastInsertId()
+
+	rrPatch := do(http.MethodPatch, "/tasks/"+strconv.FormatInt(taskID, 10), []byte(`{"assignee_id": `+outsiderStr+`}`))
+	if rrPatch.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 when assigning a non-member on patch, got %v", rrPatch.Code)
+	}
+
+	rrRepair := do(http.MethodPost, "/teams/"+teamStr+"/invalid-tasks/repair", []byte(`{"strategy": "reassign", "assignee_id": `+strconv.FormatInt(ownerID, 10)+`}`))
+	if rrRepair.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %v. Body: %s", rrRepair.Code, rrRepair.Body.String())
+	}
+
+	task, _ := queries.GetTaskByID(context.Background(), taskID)
+	if task.AssigneeID.Int64 != ownerID {
+		t.Errorf("expected task to be reassigned to owner, got %+v", task.AssigneeID)
+	}
+
+	invalid, _ := queries.FindInvalidTasks(context.Background(), db.FindInvalidTasksParams{
+		TeamID: sql.NullInt64{Int64: teamID, Valid: true},
+	})
+	if len(invalid) != 0 {
+		t.Errorf("expected no invalid tasks after repair, got %v", invalid)
+	}
+}
+
+func TestListTasksCursorPagination(t *testing.T) {
+	database, cleanupDB := setupTestDBWithTasks(t)
+	defer cleanupDB()
+	rdb, cleanupRedis := setupTestRedis(t)
+	defer cleanupRedis()
+
+	queries := db.New(database)
+	taskHandlers := NewTaskHandlers(queries, database, rdb)
+
+	resUser, _ := queries.CreateUser(context.Background(), db.CreateUserParams{
+		Email: "pager@example.com", PasswordHash: "hash",
+	})
+	userID, _ := resUser.LastInsertId()
+
+	resTeam, _ := queries.CreateTeam(context.Background(), db.CreateTeamParams{
+		Name: "Pager Team", CreatedBy: userID,
+	})
+	teamID, _ := resTeam.LastInsertId()
+	_ = queries.AddTeamMember(context.Background(), db.AddTeamMemberParams{
+		TeamID: teamID, UserID: userID, Role: "owner",
+	})
+
+	// All tasks share one created_at second, so ordering relies on the id tie-breaker.
+	for i := 0; i < 5; i++ {
+		_, _ = database.Exec(`INSERT INTO tasks (title, status, team_id, created_by, created_at)
+			VALUES (?, 'todo', ?, ?, '2024-01-01 10:00:00')`, "Task "+strconv.Itoa(i), teamID, userID)
+	}
+
+	listURL := "/tasks?team_id=" + strconv.FormatInt(teamID, 10) + "&page_size=2"
+
+	var seen []int64
+	cursor := ""
+	for pages := 0; pages < 5; pages++ {
+		url := listURL
+		if cursor != "" {
+			url += "&cursor=" + cursor
+		} else {
+			url += "&include_total=true"
+		}
+
+		rr := listTasksAs(taskHandlers, userID, url)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		var page taskPage
+		json.NewDecoder(rr.Body).Decode(&page)
+		if cursor == "" && (page.Total == nil || *page.Total != 5) {
+			t.Errorf("expected total of 5 on the first page, got %v", page.Total)
+		}
+		for _, task := range page.Items {
+			seen = append(seen, task.ID)
+		}
+
+		if page.NextCursor == nil {
+			break
+		}
+		cursor = *page.NextCursor
+	}
+
+	if len(seen) != 5 {
+		t.Fatalf("expected to walk all 5 tasks, got %v", seen)
+	}
+	for i := 1; i < len(seen); i++ {
+		if seen[i] >= seen[i-1] {
+			t.Errorf("expected tasks in descending id order without repeats, got %v", seen)
+			break
+		}
+	}
+
+	for _, bad := range []string{"&page_size=0", "&page_size=1000", "&cursor=not-a-cursor"} {
+		rr := listTasksAs(taskHandlers, userID, "/tasks?team_id="+strconv.FormatInt(teamID, 10)+bad)
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected %s to get 422, got %d", bad, rr.Code)
+		}
+	}
+}
+
+func TestListTasksFiltersAndSort(t *testing.T) {
+	database, cleanupDB := setupTestDBWithTasks(t)
+	defer cleanupDB()
+	rdb, cleanupRedis := setupTestRedis(t)
+	defer cleanupRedis()
+
+	queries := db.New(database)
+	taskHandlers := NewTaskHandlers(queries, database, rdb)
+
+	resUser, _ := queries.CreateUser(context.Background(), db.CreateUserParams{
+		Email: "filter@example.com", PasswordHash: "hash",
+	})
+	userID, _ := resUser.LastInsertId()
+	resOther, _ := queries.CreateUser(context.Background(), db.CreateUserParams{
+		Email: "filter_other@example.com", PasswordHash: "hash",
+	})
+	otherID, _ := resOther.LastInsertId()
+
+	resTeam, _ := queries.CreateTeam(context.Background(), db.CreateTeamParams{
+		Name: "Filter Team", CreatedBy: userID,
+	})
+	teamID, _ := resTeam.LastInsertId()
+	_ = queries.AddTeamMember(context.Background(), db.AddTeamMemberParams{
+		TeamID: teamID, UserID: userID, Role: "owner",
+	})
+
+	seed := []struct {
+		title, status                    string
+		description, assignee, createdBy interface{}
+		createdAt, updatedAt             string
+	}{
+		{"Deploy API", "todo", "roll out the gateway", nil, userID, "2024-01-05 10:00:00", "2024-03-01 10:00:00"},
+		{"Write docs", "in_progress", "document the deployment", otherID, userID, "2024-01-10 10:00:00", "2024-01-11 10:00:00"},
+		{"Fix login", "done", "users cannot sign in", userID, otherID, "2024-02-01 10:00:00", "2024-02-02 10:00:00"},
+		{"Audit logs", "todo", nil, otherID, otherID, "2024-02-15 10:00:00", "2024-02-20 10:00:00"},
+	}
+	for _, task := range seed {
+		_, err := database.Exec(`INSERT INTO tasks (title, description, status, team_id, assignee_id, created_by, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			task.title, task.description, task.status, teamID, task.assignee, task.createdBy, task.createdAt, task.updatedAt)
+		if err != nil {
+			t.Fatalf("failed to seed task: %v", err)
+		}
+	}
+
+	list := func(filters string) []string {
+		url := "/tasks?team_id=" + strconv.FormatInt(teamID, 10) + "&" + filters
+		rr := listTasksAs(taskHandlers, userID, url)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200 for %s, got %d: %s", filters, rr.Code, rr.Body.String())
+		}
+
+		var page taskPage
+		json.NewDecoder(rr.Body).Decode(&page)
+		titles := []string{}
+		for _, task := range page.Items {
+			titles = append(titles, task.Title)
+		}
+		return titles
+	}
+
+	tests := []struct {
+		filters string
+		want    []string
+	}{
+		{"status=todo,done&sort=title&order=asc", []string{"Audit logs", "Deploy API", "Fix login"}},
+		{"created_by=" + strconv.FormatInt(otherID, 10), []string{"Audit logs", "Fix login"}},
+		{"unassigned=true", []string{"Deploy API"}},
+		{"created_from=2024-01-10&created_to=2024-02-01", []string{"Write docs"}},
+		{"updated_from=2024-02-01&sort=updated_at&order=desc", []string{"Deploy API", "Audit logs", "Fix login"}},
+		{"q=deploy", []string{"Write docs", "Deploy API"}},
+		{"q=gateway+roll", []string{"Deploy API"}},
+		{"sort=status&order=asc", []string{"Deploy API", "Audit logs", "Write docs", "Fix login"}},
+	}
+	for _, tt := range tests {
+		got := list(tt.filters)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s: expected %v, got %v", tt.filters, tt.want, got)
+		}
+	}
+
+	var walked []string
+	cursor := ""
+	for pages := 0; pages < 5; pages++ {
+		url := "/tasks?team_id=" + strconv.FormatInt(teamID, 10) + "&sort=title&order=asc&page_size=3"
+		if cursor != "" {
+			url += "&cursor=" + cursor
+		}
+		rr := listTasksAs(taskHandlers, userID, url)
+
+		var page taskPage
+		json.NewDecoder(rr.Body).Decode(&page)
+		for _, task := range page.Items {
+			walked = append(walked, task.Title)
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		cursor = *page.NextCursor
+	}
+	if strings.Join(walked, "|") != "Audit logs|Deploy API|Fix login|Write docs" {
+		t.Errorf("expected title-sorted walk over all tasks, got %v", walked)
+	}
+
+	rr := listTasksAs(taskHandlers, userID, "/tasks?team_id="+strconv.FormatInt(teamID, 10)+"&cursor="+cursor)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected a title cursor to be rejected for the default sort, got %d", rr.Code)
+	}
+}
+
+func TestMyTasks(t *testing.T) {
+	database, cleanupDB := setupTestDBWithTasks(t)
+	defer cleanupDB()
+
+	ctx := context.Background()
+	queries := db.New(database)
+	taskHandlers := NewTaskHandlers(queries, database, nil)
+
+	createUser := func(email string) int64 {
+		res, _ := queries.CreateUser(ctx, db.CreateUserParams{Email: email, PasswordHash: "hash"})
+		id, _ := res.LastInsertId()
+		return id
+	}
+	meID := createUser("me@example.com")
+	otherID := createUser("colleague@example.com")
+	loneID := createUser("lone@example.com")
+
+	createTeam := func(name string, members ...int64) int64 {
+		res, _ := queries.CreateTeam(ctx, db.CreateTeamParams{Name: name, CreatedBy: members[0]})
+		id, _ := res.LastInsertId()
+		for _, m := range members {
+			_ = queries.AddTeamMember(ctx, db.AddTeamMemberParams{TeamID: id, UserID: m, Role: "member"})
+		}
+		return id
+	}
+	teamA := createTeam("Team A", meID, otherID)
+	teamB := createTeam("Team B", otherID, meID)
+	foreignTeam := createTeam("Foreign", otherID)
+
+	createTask := func(title string, teamID, createdBy int64, assignee sql.NullInt64) {
+		_, err := queries.CreateTask(ctx, db.CreateTaskParams{
+			Title: title, Status: "todo", TeamID: teamID, CreatedBy: createdBy, AssigneeID: assignee,
+		})
+		if err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+	me := sql.NullInt64{Int64: meID, Valid: true}
+	createTask("Created by me", teamA, meID, sql.NullInt64{})
+	createTask("Assigned to me", teamB, otherID, me)
+	createTask("Not mine", teamB, otherID, sql.NullInt64{})
+	// Tasks of teams the caller has left do not show up even if still assigned.
+	createTask("Foreign team", foreignTeam, otherID, me)
+
+	list := func(userID int64, query string) (*httptest.ResponseRecorder, []string) {
+		req := httptest.NewRequest(http.MethodGet, "/me/tasks?"+query, nil)
+		req = req.WithContext(id_helper.WithUserID(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		taskHandlers.MyTasks(rr, req)
+
+		var page taskPage
+		json.NewDecoder(rr.Body).Decode(&page)
+		titles := []string{}
+		for _, task := range page.Items {
+			titles = append(titles, task.Title)
+		}
+		return rr, titles
+	}
+
+	rr, titles := list(meID, "sort=title&order=asc&include_total=true")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	if strings.Join(titles, "|") != "Assigned to me|Created by me" {
+		t.Errorf("expected own tasks from both teams, got %v", titles)
+	}
+
+	if _, titles := list(meID, "team_id="+strconv.FormatInt(teamA, 10)); strings.Join(titles, "|") != "Created by me" {
+		t.Errorf("expected team_id to narrow the list, got %v", titles)
+	}
+	if rr, _ := list(meID, "team_id="+strconv.FormatInt(foreignTeam, 10)); rr.Code != http.StatusForbidden {
+		t.Errorf("expected a foreign team_id to get 403, got %d", rr.Code)
+	}
+	if rr, _ := list(meID, "status=Later"); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected invalid filter to get 422, got %d", rr.Code)
+	}
+
+	rr, titles = list(loneID, "")
+	if rr.Code != http.StatusOK || len(titles) != 0 {
+		t.Errorf("expected an empty page for a user without teams, got %d %v", rr.Code, titles)
+	}
+}
+
+func TestBoardAndMoveTask(t *testing.T) {
+	database, cleanupDB := setupTestDBWithTasks(t)
+	defer cleanupDB()
+	rdb, cleanupRedis := setupTestRedis(t)
+	defer cleanupRedis()
+
+	ctx := context.Background()
+	queries := db.New(database)
+	taskHandlers := NewTaskHandlers(queries, database, rdb)
+
+	resUser, _ := queries.CreateUser(ctx, db.CreateUserParams{Email: "board@example.com", PasswordHash: "hash"})
+	userID, _ := resUser.LastInsertId()
+	resOutsider, _ := queries.CreateUser(ctx, db.CreateUserParams{Email: "outsider@example.com", PasswordHash: "hash"})
+	outsiderID, _ := resOutsider.LastInsertId()
+
+	resTeam, _ := queries.CreateTeam(ctx, db.CreateTeamParams{Name: "Board Team", CreatedBy: userID})
+	teamID, _ := resTeam.LastInsertId()
+	_ = queries.AddTeamMember(ctx, db.AddTeamMemberParams{TeamID: teamID, UserID: userID, Role: "owner"})
+	team := strconv.FormatInt(teamID, 10)
+
+	call := func(handler http.HandlerFunc, asUser int64, method, url, body, idParam string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", idParam)
+		req = req.WithContext(context.WithValue(id_helper.WithUserID(req.Context(), asUser), chi.RouteCtxKey, rctx))
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	ids := map[string]int64{}
+	for _, title := range []string{"A", "B", "C", "D"} {
+		rr := call(taskHandlers.CreateTask, userID, http.MethodPost, "/tasks",
+			`{"title": "`+title+`", "status": "todo", "team_id": `+team+`}`, "")
+		var created map[string]int64
+		json.NewDecoder(rr.Body).Decode(&created)
+		ids[title] = created["task_id"]
+	}
+
+	board := func(query string) map[string][]string {
+		rr := call(taskHandlers.Board, userID, http.MethodGet, "/teams/"+team+"/board"+query, "", team)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected board, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Columns []boardColumn `json:"columns"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		columns := map[string][]string{}
+		for _, c := range resp.Columns {
+			titles := []string{}
+			for _, task := range c.Tasks {
+				titles = append(titles, task.Title)
+			}
+			columns[c.Status] = titles
+		}
+		return columns
+	}
+	move := func(title, body string) *httptest.ResponseRecorder {
+		id := strconv.FormatInt(ids[title], 10)
+		return call(taskHandlers.MoveTask, userID, http.MethodPost, "/tasks/"+id+"/move", body, id)
+	}
+
+	if got := board(""); strings.Join(got["todo"], "") != "ABCD" || len(got["done"]) != 0 {
+		t.Fatalf("expected new tasks at the bottom of todo, got %v", got)
+	}
+
+	if rr := move("D", `{"position": 0}`); rr.Code != http.StatusOK {
+		t.Fatalf("expected move within the column, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := move("A", `{"status": "in_progress", "position": 5}`); rr.Code != http.StatusOK {
+		t.Fatalf("expected move to another column, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := move("C", `{"status": "in_progress", "position": 0}`); rr.Code != http.StatusOK {
+		t.Fatalf("expected move to another column, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	got := board("")
+	if strings.Join(got["todo"], "") != "DB" || strings.Join(got["in_progress"], "") != "CA" {
+		t.Errorf("unexpected board after moves: %v", got)
+	}
+	if got := board("?limit=1"); len(got["todo"]) != 1 {
+		t.Errorf("expected limit to cap each column, got %v", got)
+	}
+
+	var status string
+	database.QueryRow("SELECT status FROM tasks WHERE id = ?", ids["A"]).Scan(&status)
+	if status != "in_progress" {
+		t.Errorf("expected move to change the status, got %s", status)
+	}
+
+	var moves, statusUpdates int
+	database.QueryRow("SELECT COUNT(*) FROM task_history WHERE change_type = 'moved'").Scan(&moves)
+	database.QueryRow("SELECT COUNT(*) FROM task_history WHERE change_type = 'status_update' AND task_id = ?", ids["A"]).Scan(&statusUpdates)
+	if moves != 3 || statusUpdates != 1 {
+		t.Errorf("expected 3 moves and a status update in history, got %d and %d", moves, statusUpdates)
+	}
+
+	if rr := move("B", `{"position": 1}`); rr.Code != http.StatusOK {
+		t.Errorf("expected a no-op move to succeed, got %d", rr.Code)
+	}
+	database.QueryRow("SELECT COUNT(*) FROM task_history WHERE change_type = 'moved'").Scan(&moves)
+	if moves != 3 {
+		t.Errorf("expected a no-op move not to be logged, got %d moves", moves)
+	}
+
+	// Changing the status via PATCH appends the task to its new column.
+	b := strconv.FormatInt(ids["B"], 10)
+	call(taskHandlers.PatchTask, userID, http.MethodPatch, "/tasks/"+b, `{"status": "in_progress"}`, b)
+	if got := board(""); strings.Join(got["in_progress"], "") != "CAB" {
+		t.Errorf("expected patched task at the bottom of its column, got %v", got)
+	}
+
+	if rr := move("A", `{"position": -1}`); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected negative position to get 422, got %d", rr.Code)
+	}
+	if rr := move("A", `{"status": "later", "position": 0}`); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected invalid status to get 422, got %d", rr.Code)
+	}
+
+	a := strconv.FormatInt(ids["A"], 10)
+	if rr := call(taskHandlers.MoveTask, outsiderID, http.MethodPost, "/tasks/"+a+"/move", `{"position": 0}`, a); rr.Code != http.StatusForbidden {
+		t.Errorf("expected outsider move to get 403, got %d", rr.Code)
+	}
+	if rr := call(taskHandlers.Board, outsiderID, http.MethodGet, "/teams/"+team+"/board", "", team); rr.Code != http.StatusForbidden {
+		t.Errorf("expected outsider board to get 403, got %d", rr.Code)
+	}
+}
+
+func TestTeamWorkflow(t *testing.T) {
+	database, cleanupDB := setupTestDBWithTasks(t)
+	defer cleanupDB()
+	rdb, cleanupRedis := setupTestRedis(t)
+	defer cleanupRedis()
+
+	ctx := context.Background()
+	queries := db.New(database)
+	taskHandlers := NewTaskHandlers(queries, database, rdb)
+	teamHandlers := NewTeamHandlers(queries, database, rdb)
+
+	resOwner, _ := queries.CreateUser(ctx, db.CreateUserParams{Email: "flow-owner@example.com", PasswordHash: "hash"})
+	ownerID, _ := resOwner.LastInsertId()
+	resMember, _ := queries.CreateUser(ctx, db.CreateUserParams{Email: "flow-member@example.com", PasswordHash: "hash"})
+	memberID, _ := resMember.LastInsertId()
+
+	resTeam, _ := queries.CreateTeam(ctx, db.CreateTeamParams{Name: "Flow Team", CreatedBy: ownerID})
+	teamID, _ := resTeam.LastInsertId()
+	_ = queries.AddTeamMember(ctx, db.AddTeamMemberParams{TeamID: teamID, UserID: ownerID, Role: "owner"})
+	_ = queries.AddTeamMember(ctx, db.AddTeamMemberParams{TeamID: teamID, UserID: memberID, Role: "member"})
+	team := strconv.FormatInt(teamID, 10)
+
+	call := func(handler http.HandlerFunc, asUser int64, method, url, body, idParam string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", idParam)
+		req = req.WithContext(context.WithValue(id_helper.WithUserID(req.Context(), asUser), chi.RouteCtxKey, rctx))
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+	putWorkflow := func(asUser int64, body string) *httptest.ResponseRecorder {
+		return call(teamHandlers.UpdateWorkflow, asUser, http.MethodPut, "/teams/"+team+"/workflow", body, team)
+	}
+	createTask := func(status string) string {
+		rr := call(taskHandlers.CreateTask, memberID, http.MethodPost, "/tasks",
+			`{"title": "T", "status": "`+status+`", "team_id": `+team+`}`, "")
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected task in %s to be created, got %d: %s", status, rr.Code, rr.Body.String())
+		}
+		var created map[string]int64
+		json.NewDecoder(rr.Body).Decode(&created)
+		return strconv.FormatInt(created["task_id"], 10)
+	}
+
+	rr := call(teamHandlers.GetWorkflow, memberID, http.MethodGet, "/teams/"+team+"/workflow", "", team)
+	var wf workflow
+	json.NewDecoder(rr.Body).Decode(&wf)
+	if rr.Code != http.StatusOK || strings.Join(wf.Statuses, ",") != "todo,in_progress,done" || len(wf.Transitions) != 0 {
+		t.Fatalf("expected the default workflow, got %d %+v", rr.Code, wf)
+	}
+
+	custom := `{"statuses": ["todo", "in_progress", "review", "done", "blocked"], "transitions": [
+		{"from": "todo", "to": "in_progress"}, {"from": "in_progress", "to": "review"},
+		{"from": "review", "to": "done"}, {"from": "review", "to": "in_progress"},
+		{"from": "in_progress", "to": "blocked"}, {"from": "blocked", "to": "in_progress"}]}`
+
+	if rr := putWorkflow(memberID, custom); rr.Code != http.StatusForbidden {
+		t.Errorf("expected member to get 403, got %d", rr.Code)
+	}
+	if rr := putWorkflow(ownerID, `{"statuses": ["todo", "review"]}`); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected workflow without done to get 422, got %d", rr.Code)
+	}
+
+	inProgress := createTask("in_progress")
+	if rr := putWorkflow(ownerID, `{"statuses": ["todo", "done"]}`); rr.Code != http.StatusConflict {
+		t.Errorf("expected removing a used status to get 409, got %d", rr.Code)
+	}
+
+	if rr := putWorkflow(ownerID, custom); rr.Code != http.StatusOK {
+		t.Fatalf("expected workflow to be saved, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	task := createTask("todo")
+	rr = call(taskHandlers.CreateTask, memberID, http.MethodPost, "/tasks", `{"title": "T", "status": "review", "team_id": `+team+`}`, "")
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected a new task outside todo to get 422 once transitions are set, got %d", rr.Code)
+	}
+	patch := func(id, status string) int {
+		return call(taskHandlers.PatchTask, memberID, http.MethodPatch, "/tasks/"+id, `{"status": "`+status+`"}`, id).Code
+	}
+	if code := patch(task, "done"); code != http.StatusUnprocessableEntity {
+		t.Errorf("expected a transition outside the workflow to get 422, got %d", code)
+	}
+	if code := patch(task, "archived"); code != http.StatusUnprocessableEntity {
+		t.Errorf("expected an unknown status to get 422, got %d", code)
+	}
+	if code := patch(task, "in_progress"); code != http.StatusOK {
+		t.Errorf("expected an allowed transition to succeed, got %d", code)
+	}
+	rr = call(taskHandlers.UpdateTask, memberID, http.MethodPut, "/tasks/"+task, `{"title": "T", "status": "review"}`, task)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected update to a custom status to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = call(taskHandlers.MoveTask, memberID, http.MethodPost, "/tasks/"+inProgress+"/move", `{"status": "done", "position": 0}`, inProgress)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected a disallowed move to get 422, got %d", rr.Code)
+	}
+
+	rr = call(taskHandlers.Board, memberID, http.MethodGet, "/teams/"+team+"/board", "", team)
+	var board struct {
+		Columns []boardColumn `json:"columns"`
+	}
+	json.NewDecoder(rr.Body).Decode(&board)
+	var columns []string
+	for _, c := range board.Columns {
+		columns = append(columns, c.Status)
+	}
+	if strings.Join(columns, ",") != "todo,in_progress,review,done,blocked" {
+		t.Errorf("expected board columns in workflow order, got %v", columns)
+	}
+
+	if rr := putWorkflow(ownerID, `{"statuses": ["todo", "in_progress", "done"]}`); rr.Code != http.StatusConflict {
+		t.Errorf("expected removing review while it has tasks to get 409, got %d", rr.Code)
+	}
 }
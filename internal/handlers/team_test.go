@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/egor_lukyanovich/moon_test_application/internal/db"
 	id_helper "github.com/egor_lukyanovich/moon_test_application/pkg/routing"
@@ -53,7 +54,9 @@ func setupTestDB(t *testing.T) (*sql.DB, func()) {
 		id BIGINT AUTO_INCREMENT PRIMARY KEY,
 		email VARCHAR(255) NOT NULL UNIQUE,
 		password_hash VARCHAR(255) NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+		display_name VARCHAR(100)
 	);
 	CREATE TABLE teams (
 		id BIGINT AUTO_INCREMENT PRIMARY KEY,
@@ -67,6 +70,18 @@ func setupTestDB(t *testing.T) (*sql.DB, func()) {
 		role ENUM('owner', 'admin', 'member') NOT NULL,
 		joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		PRIMARY KEY (team_id, user_id)
+	);
+	CREATE TABLE team_invitations (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		team_id BIGINT NOT NULL,
+		email VARCHAR(255) NOT NULL,
+		user_id BIGINT NULL,
+		role ENUM('admin', 'member') NOT NULL DEFAULT 'member',
+		status ENUM('pending', 'accepted', 'declined') NOT NULL DEFAULT 'pending',
+		invited_by BIGINT NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		responded_at TIMESTAMP NULL
 	);`
 	_, err = database.Exec(schema)
 	if err != nil {
@@ -86,7 +101,7 @@ func TestCreateTeam(t *testing.T) {
 	defer cleanup()
 
 	queries := db.New(database)
-	teamHandlers := NewTeamHandlers(queries, database)
+	teamHandlers := NewTeamHandlers(queries, database, nil)
 
 	res, _ := queries.CreateUser(context.Background(), db.CreateUserParams{
 		Email:        "test@example.com",
@@ -129,7 +144,7 @@ func TestListTeams(t *testing.T) {
 	defer cleanup()
 
 	queries := db.New(database)
-	teamHandlers := NewTeamHandlers(queries, database)
+	teamHandlers := NewTeamHandlers(queries, database, nil)
 
 	res, _ := queries.CreateUser(context.Background(), db.CreateUserParams{
 		Email:        "list@example.com",
@@ -172,7 +187,7 @@ func TestInviteToTeam(t *testing.T) {
 	defer cleanup()
 
 	queries := db.New(database)
-	teamHandlers := NewTeamHandlers(queries, database)
+	teamHandlers := NewTeamHandlers(queries, database, nil)
 
 	resInviter, _ := queries.CreateUser(context.Background(), db.CreateUserParams{
 		Email:        "owner@example.com",
@@ -202,18 +217,50 @@ func TestInviteToTeam(t *testing.T) {
 
 	r := chi.NewRouter()
 	r.Post("/teams/{id}/invite", teamHandlers.InviteToTeam)
+	r.Get("/invitations", teamHandlers.ListInvitations)
+	r.Post("/invitations/{id}/accept", teamHandlers.AcceptInvitation)
+	r.Post("/invitations/{id}/decline", teamHandlers.DeclineInvitation)
+
+	do := func(method, url string, body []byte, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req = req.WithContext(id_helper.WithUserID(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
 
 	url := "/teams/" + strconv.FormatInt(teamID, 10) + "/invite"
-	req := httptest.NewRequest(http.MethodPost, url, bytes.NewBuffer(reqBody))
+	rr := do(http.MethodPost, url, reqBody, inviterID)
 
-	ctx := id_helper.WithUserID(req.Context(), inviterID)
-	req = req.WithContext(ctx)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %v; got %v. Body: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
 
-	rr := httptest.NewRecorder()
-	r.ServeHTTP(rr, req)
+	var created map[string]interface{}
+	json.NewDecoder(rr.Body).Decode(&created)
+	invitationURL := "/invitations/" + strconv.FormatInt(int64(created["invitation_id"].(float64)), 10)
 
-	if rr.Code != http.StatusOK {
-		t.Errorf("expected status %v; got %v. Body: %s", http.StatusOK, rr.Code, rr.Body.String())
+	if id_helper.CheckTeamRole(context.Background(), queries, teamID, inviteeID) {
+		t.Errorf("expected invitee not to join before accepting")
+	}
+
+	if rr := do(http.MethodPost, url, reqBody, inviterID); rr.Code != http.StatusConflict {
+		t.Errorf("expected duplicate invitation to get 409, got %d", rr.Code)
+	}
+
+	rrList := do(http.MethodGet, "/invitations", nil, inviteeID)
+	var pending []db.ListUserPendingInvitationsRow
+	json.NewDecoder(rrList.Body).Decode(&pending)
+	if len(pending) != 1 || pending[0].TeamID != teamID {
+		t.Errorf("expected one pending invitation to team %d, got %+v", teamID, pending)
+	}
+
+	if rr := do(http.MethodPost, invitationURL+"/accept", nil, inviterID); rr.Code != http.StatusNotFound {
+		t.Errorf("expected someone else's invitation to get 404, got %d", rr.Code)
+	}
+
+	if rr := do(http.MethodPost, invitationURL+"/accept", nil, inviteeID); rr.Code != http.StatusOK {
+		t.Fatalf("expected accept to succeed, got %d: %s", rr.Code, rr.Body.String())
 	}
 
 	role, err := queries.GetUserRoleInTeam(context.Background(), db.GetUserRoleInTeamParams{
@@ -223,4 +270,146 @@ func TestInviteToTeam(t *testing.T) {
 	if err != nil || string(role) != "member" {
 		t.Errorf("expected invitee to be member, got role: %v, err: %v", role, err)
 	}
+
+	if rr := do(http.MethodPost, invitationURL+"/decline", nil, inviteeID); rr.Code != http.StatusConflict {
+		t.Errorf("expected answered invitation to get 409, got %d", rr.Code)
+	}
+
+	if rr := do(http.MethodPost, url, reqBody, inviterID); rr.Code != http.StatusConflict {
+		t.Errorf("expected inviting a member to get 409, got %d", rr.Code)
+	}
+
+	rrEmail := do(http.MethodPost, url, []byte(`{"email": "newcomer@example.com", "role": "admin"}`), inviterID)
+	if rrEmail.Code != http.StatusCreated {
+		t.Fatalf("expected email invitation to succeed, got %d: %s", rrEmail.Code, rrEmail.Body.String())
+	}
+	json.NewDecoder(rrEmail.Body).Decode(&created)
+	emailInvitation, _ := queries.GetInvitationByID(context.Background(), int64(created["invitation_id"].(float64)))
+	if emailInvitation.UserID.Valid || emailInvitation.Email != "newcomer@example.com" {
+		t.Errorf("expected invitation waiting for registration, got %+v", emailInvitation)
+	}
+
+	resAdmin, _ := queries.CreateUser(context.Background(), db.CreateUserParams{
+		Email:        "admin@example.com",
+		PasswordHash: "hash",
+	})
+	adminID, _ := resAdmin.LastInsertId()
+	_ = queries.AddTeamMember(context.Background(), db.AddTeamMemberParams{
+		TeamID: teamID,
+		UserID: adminID,
+		Role:   "admin",
+	})
+
+	if rr := do(http.MethodPost, url, []byte(`{"email": "sidekick@example.com", "role": "admin"}`), adminID); rr.Code != http.StatusForbidden {
+		t.Errorf("expected admin inviting an admin to get 403, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPost, url, []byte(`{"email": "sidekick@example.com", "role": "member"}`), adminID); rr.Code != http.StatusCreated {
+		t.Errorf("expected admin inviting a member to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	resExpired, _ := queries.CreateInvitation(context.Background(), db.CreateInvitationParams{
+		TeamID:    teamID,
+		Email:     "owner@example.com",
+		UserID:    sql.NullInt64{Int64: inviterID, Valid: true},
+		Role:      "member",
+		InvitedBy: inviterID,
+		ExpiresAt: time.Now().Add(-time.Hour),
+	})
+	expiredID, _ := resExpired.LastInsertId()
+	if rr := do(http.MethodPost, "/invitations/"+strconv.FormatInt(expiredID, 10)+"/accept", nil, inviterID); rr.Code != http.StatusConflict {
+		t.Errorf("expected expired invitation to get 409, got %d", rr.Code)
+	}
+}
+
+func TestTeamOwnershipAndDeletion(t *testing.T) {
+	database, cleanupDB := setupTestDBWithTasks(t)
+	defer cleanupDB()
+	rdb, cleanupRedis := setupTestRedis(t)
+	defer cleanupRedis()
+
+	ctx := context.Background()
+	queries := db.New(database)
+	teamHandlers := NewTeamHandlers(queries, database, rdb)
+
+	resOwner, _ := queries.CreateUser(ctx, db.CreateUserParams{Email: "owner@example.com", PasswordHash: "hash"})
+	ownerID, _ := resOwner.LastInsertId()
+	resMember, _ := queries.CreateUser(ctx, db.CreateUserParams{Email: "member@example.com", PasswordHash: "hash"})
+	memberID, _ := resMember.LastInsertId()
+
+	resTeam, _ := queries.CreateTeam(ctx, db.CreateTeamParams{Name: "Old Name", CreatedBy: ownerID})
+	teamID, _ := resTeam.LastInsertId()
+
+	_ = queries.AddTeamMember(ctx, db.AddTeamMemberParams{TeamID: teamID, UserID: ownerID, Role: "owner"})
+	_ = queries.AddTeamMember(ctx, db.AddTeamMemberParams{TeamID: teamID, UserID: memberID, Role: "member"})
+
+	resTask, _ := queries.CreateTask(ctx, db.CreateTaskParams{
+		Title: "Doomed", Status: "todo", TeamID: teamID, CreatedBy: ownerID,
+	})
+	taskID, _ := resTask.LastInsertId()
+	_, _ = queries.CreateTaskComment(ctx, db.CreateTaskCommentParams{TaskID: taskID, UserID: ownerID, Content: "bye"})
+
+	r := chi.NewRouter()
+	r.Patch("/teams/{id}", teamHandlers.UpdateTeam)
+	r.Delete("/teams/{id}", teamHandlers.DeleteTeam)
+	r.Post("/teams/{id}/transfer-ownership", teamHandlers.TransferOwnership)
+
+	teamURL := "/teams/" + strconv.FormatInt(teamID, 10)
+	do := func(method, url string, body []byte, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req = req.WithContext(id_helper.WithUserID(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := do(http.MethodPatch, teamURL, []byte(`{"name": "Renamed"}`), memberID); rr.Code != http.StatusForbidden {
+		t.Errorf("expected member rename to get 403, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPatch, teamURL, []byte(`{"name": ""}`), ownerID); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected empty name to get 422, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPatch, teamURL, []byte(`{"name": "Renamed"}`), ownerID); rr.Code != http.StatusOK {
+		t.Errorf("expected rename to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if team, _ := queries.GetTeamByID(ctx, teamID); team.Name != "Renamed" {
+		t.Errorf("expected team to be renamed, got %q", team.Name)
+	}
+
+	transferBody := []byte(`{"user_id": ` + strconv.FormatInt(memberID, 10) + `}`)
+	if rr := do(http.MethodPost, teamURL+"/transfer-ownership", transferBody, memberID); rr.Code != http.StatusForbidden {
+		t.Errorf("expected non-owner transfer to get 403, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPost, teamURL+"/transfer-ownership", transferBody, ownerID); rr.Code != http.StatusOK {
+		t.Fatalf("expected transfer to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	newOwnerRole, _ := queries.GetUserRoleInTeam(ctx, db.GetUserRoleInTeamParams{TeamID: teamID, UserID: memberID})
+	oldOwnerRole, _ := queries.GetUserRoleInTeam(ctx, db.GetUserRoleInTeamParams{TeamID: teamID, UserID: ownerID})
+	if newOwnerRole != db.TeamMembersRoleOwner || oldOwnerRole != db.TeamMembersRoleAdmin {
+		t.Errorf("expected roles to be swapped, got new=%s old=%s", newOwnerRole, oldOwnerRole)
+	}
+
+	if rr := do(http.MethodDelete, teamURL, nil, ownerID); rr.Code != http.StatusForbidden {
+		t.Errorf("expected former owner delete to get 403, got %d", rr.Code)
+	}
+
+	rrDelete := do(http.MethodDelete, teamURL, nil, memberID)
+	if rrDelete.Code != http.StatusOK {
+		t.Fatalf("expected delete to succeed, got %d: %s", rrDelete.Code, rrDelete.Body.String())
+	}
+
+	var deleted struct {
+		Deleted map[string]int64 `json:"deleted"`
+	}
+	json.NewDecoder(rrDelete.Body).Decode(&deleted)
+	if deleted.Deleted["tasks"] != 1 || deleted.Deleted["comments"] != 1 || deleted.Deleted["members"] != 2 {
+		t.Errorf("unexpected deletion report: %+v", deleted.Deleted)
+	}
+
+	if _, err := queries.GetTeamByID(ctx, teamID); err == nil {
+		t.Errorf("expected team to be deleted")
+	}
+	if _, err := queries.GetTaskByID(ctx, taskID); err == nil {
+		t.Errorf("expected team tasks to be deleted")
+	}
 }
@@ -5,20 +5,25 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+	models "github.com/egor_lukyanovich/moon_test_application/internal/models"
 	json_resp "github.com/egor_lukyanovich/moon_test_application/pkg/json"
 	id_helper "github.com/egor_lukyanovich/moon_test_application/pkg/routing"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/validation"
 	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
 )
 
 type TeamHandlers struct {
-	q  *db.Queries
-	db *sql.DB
+	q     *db.Queries
+	db    *sql.DB
+	cache taskListCache
 }
 
-func NewTeamHandlers(q *db.Queries, database *sql.DB) *TeamHandlers {
-	return &TeamHandlers{q: q, db: database}
+func NewTeamHandlers(q *db.Queries, database *sql.DB, redisClient *redis.Client) *TeamHandlers {
+	return &TeamHandlers{q: q, db: database, cache: taskListCache{redis: redisClient}}
 }
 
 func (h *TeamHandlers) CreateTeam(w http.ResponseWriter, r *http.Request) {
@@ -31,8 +36,16 @@ func (h *TeamHandlers) CreateTeam(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Name string `json:"name"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
-		json_resp.RespondError(w, 400, "BAD_REQUEST", "invalid json or empty name")
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json_resp.RespondError(w, 400, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	v := validation.New()
+	v.Required("name", req.Name)
+	v.MaxLength("name", req.Name, maxTeamNameLength)
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
 		return
 	}
 
@@ -95,6 +108,8 @@ func (h *TeamHandlers) ListTeams(w http.ResponseWriter, r *http.Request) {
 	json_resp.RespondJSON(w, 200, teams)
 }
 
+// InviteToTeam creates a pending invitation. The invitee can be addressed by
+// user_id or by email; an email without an account waits for registration.
 func (h *TeamHandlers) InviteToTeam(w http.ResponseWriter, r *http.Request) {
 	inviterID, ok := id_helper.GetUserIDHelper(r.Context())
 	if !ok {
@@ -115,6 +130,7 @@ func (h *TeamHandlers) InviteToTeam(w http.ResponseWriter, r *http.Request) {
 
 	var req struct {
 		UserID int64  `json:"user_id"`
+		Email  string `json:"email"`
 		Role   string `json:"role"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -122,15 +138,270 @@ func (h *TeamHandlers) InviteToTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.q.AddTeamMember(r.Context(), db.AddTeamMemberParams{
+	v := validation.New()
+	var invitee sql.NullInt64
+	switch {
+	case req.UserID != 0:
+		v.Positive("user_id", req.UserID)
+		v.Check(req.Email == "", "email", "must not be set together with user_id")
+		if user, err := h.q.GetUserByID(r.Context(), req.UserID); err == nil {
+			req.Email = user.Email
+			invitee = sql.NullInt64{Int64: user.ID, Valid: true}
+		} else {
+			v.Check(false, "user_id", "must reference an existing user")
+		}
+	default:
+		v.Email("email", req.Email)
+		if user, err := h.q.GetUserByEmail(r.Context(), req.Email); err == nil {
+			invitee = sql.NullInt64{Int64: user.ID, Valid: true}
+		}
+	}
+	v.Required("role", req.Role)
+	v.OneOf("role", req.Role, inviteRoles...)
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
+	// Inviting as admin is a promotion, which like UpdateMemberRole is owner-only.
+	if req.Role == string(db.TeamMembersRoleAdmin) && !id_helper.CheckTeamRole(r.Context(), h.q, teamID, inviterID, "owner") {
+		json_resp.RespondError(w, 403, "FORBIDDEN", "only owner can invite admins")
+		return
+	}
+
+	if invitee.Valid && id_helper.CheckTeamRole(r.Context(), h.q, teamID, invitee.Int64) {
+		json_resp.RespondError(w, 409, "CONFLICT", "user is already a member of this team")
+		return
+	}
+
+	_, err = h.q.GetPendingInvitation(r.Context(), db.GetPendingInvitationParams{
+		TeamID: teamID,
+		Email:  req.Email,
+	})
+	if err == nil {
+		json_resp.RespondError(w, 409, "CONFLICT", "user already has a pending invitation to this team")
+		return
+	}
+
+	expiresAt := time.Now().Add(invitationTTL)
+	res, err := h.q.CreateInvitation(r.Context(), db.CreateInvitationParams{
+		TeamID:    teamID,
+		Email:     req.Email,
+		UserID:    invitee,
+		Role:      db.TeamInvitationsRole(req.Role),
+		InvitedBy: inviterID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to create invitation")
+		return
+	}
+
+	invitationID, _ := res.LastInsertId()
+
+	json_resp.RespondJSON(w, 201, map[string]interface{}{
+		"invitation_id": invitationID,
+		"email":         req.Email,
+		"status":        db.TeamInvitationsStatusPending,
+		"expires_at":    expiresAt,
+	})
+}
+
+func (h *TeamHandlers) UpdateTeam(w http.ResponseWriter, r *http.Request) {
+	userID, teamID, ok := teamRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if !id_helper.CheckTeamRole(r.Context(), h.q, teamID, userID, "owner", "admin") {
+		json_resp.RespondError(w, 403, "FORBIDDEN", "only owner or admin can rename the team")
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json_resp.RespondError(w, 400, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	v := validation.New()
+	v.Required("name", req.Name)
+	v.MaxLength("name", req.Name, maxTeamNameLength)
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
+	if err := h.q.UpdateTeamName(r.Context(), db.UpdateTeamNameParams{Name: req.Name, ID: teamID}); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to rename team")
+		return
+	}
+
+	team, err := h.q.GetTeamByID(r.Context(), teamID)
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to fetch team")
+		return
+	}
+
+	json_resp.RespondJSON(w, 200, team)
+}
+
+// TransferOwnership makes another member an owner and demotes the caller to
+// admin in one transaction, so the team never ends up without an owner.
+func (h *TeamHandlers) TransferOwnership(w http.ResponseWriter, r *http.Request) {
+	userID, teamID, ok := teamRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if !id_helper.CheckTeamRole(r.Context(), h.q, teamID, userID, "owner") {
+		json_resp.RespondError(w, 403, "FORBIDDEN", "only team owner can transfer ownership")
+		return
+	}
+
+	var req struct {
+		UserID int64 `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json_resp.RespondError(w, 400, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	v := validation.New()
+	v.Positive("user_id", req.UserID)
+	v.Check(req.UserID != userID, "user_id", "must be another member of the team")
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to start tx")
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.q.WithTx(tx)
+
+	if !lockTeamMembers(w, r, qtx, teamID) {
+		return
+	}
+
+	// Checked again under the lock: the caller may have been demoted since.
+	if !id_helper.CheckTeamRole(r.Context(), qtx, teamID, userID, "owner") {
+		json_resp.RespondError(w, 403, "FORBIDDEN", "only team owner can transfer ownership")
+		return
+	}
+
+	role, err := qtx.GetUserRoleInTeam(r.Context(), db.GetUserRoleInTeamParams{TeamID: teamID, UserID: req.UserID})
+	if err != nil {
+		json_resp.RespondValidationError(w, []models.FieldError{{Field: "user_id", Message: "must be another member of the team"}})
+		return
+	}
+	if role == db.TeamMembersRoleOwner {
+		json_resp.RespondError(w, 409, "CONFLICT", "user is already an owner of this team")
+		return
+	}
+
+	err = qtx.UpdateTeamMemberRole(r.Context(), db.UpdateTeamMemberRoleParams{
+		Role:   db.TeamMembersRoleOwner,
 		TeamID: teamID,
 		UserID: req.UserID,
-		Role:   db.TeamMembersRole(req.Role),
 	})
 	if err != nil {
-		json_resp.RespondError(w, 409, "CONFLICT", "user already in team or not found")
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to promote new owner")
+		return
+	}
+
+	err = qtx.UpdateTeamMemberRole(r.Context(), db.UpdateTeamMemberRoleParams{
+		Role:   db.TeamMembersRoleAdmin,
+		TeamID: teamID,
+		UserID: userID,
+	})
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to demote previous owner")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to commit tx")
+		return
+	}
+
+	json_resp.RespondJSON(w, 200, map[string]interface{}{
+		"team_id":             teamID,
+		"owner_id":            req.UserID,
+		"previous_owner_id":   userID,
+		"previous_owner_role": db.TeamMembersRoleAdmin,
+	})
+}
+
+// DeleteTeam removes the team with its tasks, comments, memberships and
+// invitations. Task history is kept, as it is for single task deletion.
+func (h *TeamHandlers) DeleteTeam(w http.ResponseWriter, r *http.Request) {
+	userID, teamID, ok := teamRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if !id_helper.CheckTeamRole(r.Context(), h.q, teamID, userID, "owner") {
+		json_resp.RespondError(w, 403, "FORBIDDEN", "only team owner can delete the team")
+		return
+	}
+
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to start tx")
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.q.WithTx(tx)
+
+	comments, err := qtx.DeleteTeamTaskComments(r.Context(), teamID)
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to delete comments")
+		return
+	}
+
+	tasks, err := qtx.DeleteTeamTasks(r.Context(), teamID)
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to delete tasks")
 		return
 	}
 
-	json_resp.RespondJSON(w, 200, map[string]string{"status": "invited"})
+	invitations, err := qtx.DeleteTeamInvitations(r.Context(), teamID)
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to delete invitations")
+		return
+	}
+
+	members, err := qtx.DeleteTeamMembers(r.Context(), teamID)
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to delete members")
+		return
+	}
+
+	if err := qtx.DeleteTeam(r.Context(), teamID); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to delete team")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to commit tx")
+		return
+	}
+
+	h.cache.Invalidate(r.Context(), teamID)
+
+	json_resp.RespondJSON(w, 200, map[string]interface{}{
+		"team_id": teamID,
+		"deleted": map[string]int64{
+			"tasks":       tasks,
+			"comments":    comments,
+			"members":     members,
+			"invitations": invitations,
+		},
+	})
 }
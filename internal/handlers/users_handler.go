@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+	json_resp "github.com/egor_lukyanovich/moon_test_application/pkg/json"
+	id_helper "github.com/egor_lukyanovich/moon_test_application/pkg/routing"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/validation"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type UserHandlers struct {
+	q  *db.Queries
+	db *sql.DB
+}
+
+func NewUserHandlers(q *db.Queries, database *sql.DB) *UserHandlers {
+	return &UserHandlers{q: q, db: database}
+}
+
+// userProfile never exposes the password hash; is_admin is only shown to the
+// user themselves.
+func userProfile(u db.User, self bool) map[string]interface{} {
+	profile := map[string]interface{}{
+		"id":           u.ID,
+		"email":        u.Email,
+		"display_name": nil,
+		"created_at":   u.CreatedAt.Time,
+	}
+	if u.DisplayName.Valid {
+		profile["display_name"] = u.DisplayName.String
+	}
+	if self {
+		profile["is_admin"] = u.IsAdmin
+	}
+	return profile
+}
+
+func (h *UserHandlers) GetMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := id_helper.GetUserIDHelper(r.Context())
+	if !ok {
+		json_resp.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+		return
+	}
+
+	user, err := h.q.GetUserByID(r.Context(), userID)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusNotFound, "NOT_FOUND", "user not found")
+		return
+	}
+
+	json_resp.RespondJSON(w, http.StatusOK, userProfile(user, true))
+}
+
+// GetUser shows a profile only to teammates (and system admins). Anyone else
+// gets 404, so user ids cannot be probed for existence.
+func (h *UserHandlers) GetUser(w http.ResponseWriter, r *http.Request) {
+	callerID, ok := id_helper.GetUserIDHelper(r.Context())
+	if !ok {
+		json_resp.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+		return
+	}
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid user id")
+		return
+	}
+
+	if userID != callerID && !id_helper.IsSystemAdmin(r.Context(), h.q, callerID) {
+		shared, err := h.q.UsersShareTeam(r.Context(), db.UsersShareTeamParams{UserID: callerID, UserID_2: userID})
+		if err != nil {
+			json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to check team membership")
+			return
+		}
+		if !shared {
+			json_resp.RespondError(w, http.StatusNotFound, "NOT_FOUND", "user not found")
+			return
+		}
+	}
+
+	user, err := h.q.GetUserByID(r.Context(), userID)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusNotFound, "NOT_FOUND", "user not found")
+		return
+	}
+
+	json_resp.RespondJSON(w, http.StatusOK, userProfile(user, userID == callerID))
+}
+
+// UpdateMe changes profile fields and, when new_password is given, the
+// password. A password change requires the current password and revokes every
+// refresh token of the user.
+func (h *UserHandlers) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := id_helper.GetUserIDHelper(r.Context())
+	if !ok {
+		json_resp.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+		return
+	}
+
+	var req struct {
+		DisplayName     optional[string] `json:"display_name"`
+		CurrentPassword string           `json:"current_password"`
+		NewPassword     *string          `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json_resp.RespondError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
+		return
+	}
+
+	var displayName sql.NullString
+	if req.DisplayName.Value != nil {
+		name := strings.TrimSpace(*req.DisplayName.Value)
+		displayName = sql.NullString{String: name, Valid: name != ""}
+	}
+
+	v := validation.New()
+	v.MaxLength("display_name", displayName.String, maxDisplayNameLength)
+	if req.NewPassword != nil {
+		v.Required("current_password", req.CurrentPassword)
+		v.Password("new_password", *req.NewPassword)
+	}
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
+	user, err := h.q.GetUserByID(r.Context(), userID)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusNotFound, "NOT_FOUND", "user not found")
+		return
+	}
+
+	var passwordHash []byte
+	if req.NewPassword != nil {
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)) != nil {
+			json_resp.RespondError(w, http.StatusForbidden, "FORBIDDEN", "current password is incorrect")
+			return
+		}
+
+		passwordHash, err = bcrypt.GenerateFromPassword([]byte(*req.NewPassword), bcrypt.DefaultCost)
+		if err != nil {
+			json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to hash password")
+			return
+		}
+	}
+
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to start tx")
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.q.WithTx(tx)
+
+	if req.DisplayName.Set {
+		err = qtx.UpdateUserProfile(r.Context(), db.UpdateUserProfileParams{DisplayName: displayName, ID: userID})
+		if err != nil {
+			json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update profile")
+			return
+		}
+		user.DisplayName = displayName
+	}
+
+	if passwordHash != nil {
+		err = qtx.UpdateUserPassword(r.Context(), db.UpdateUserPasswordParams{PasswordHash: string(passwordHash), ID: userID})
+		if err != nil {
+			json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update password")
+			return
+		}
+		if err := qtx.RevokeUserRefreshTokens(r.Context(), userID); err != nil {
+			json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to revoke refresh tokens")
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to commit tx")
+		return
+	}
+
+	json_resp.RespondJSON(w, http.StatusOK, userProfile(user, true))
+}
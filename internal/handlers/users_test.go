@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+	id_helper "github.com/egor_lukyanovich/moon_test_application/pkg/routing"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUserProfiles(t *testing.T) {
+	database, cleanupDB := setupTestDBWithTasks(t)
+	defer cleanupDB()
+
+	_, err := database.Exec(`CREATE TABLE refresh_tokens (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		token_hash CHAR(64) NOT NULL UNIQUE,
+		expires_at TIMESTAMP NOT NULL,
+		revoked_at TIMESTAMP NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		t.Fatalf("failed to create refresh_tokens: %v", err)
+	}
+
+	ctx := context.Background()
+	queries := db.New(database)
+	userHandlers := NewUserHandlers(queries, database)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("oldpassword"), bcrypt.MinCost)
+	createUser := func(email string) int64 {
+		res, _ := queries.CreateUser(ctx, db.CreateUserParams{Email: email, PasswordHash: string(hash)})
+		id, _ := res.LastInsertId()
+		return id
+	}
+	meID := createUser("profile@example.com")
+	mateID := createUser("mate@example.com")
+	strangerID := createUser("stranger@example.com")
+
+	resTeam, _ := queries.CreateTeam(ctx, db.CreateTeamParams{Name: "Profile Team", CreatedBy: meID})
+	teamID, _ := resTeam.LastInsertId()
+	_ = queries.AddTeamMember(ctx, db.AddTeamMemberParams{TeamID: teamID, UserID: meID, Role: "owner"})
+	_ = queries.AddTeamMember(ctx, db.AddTeamMemberParams{TeamID: teamID, UserID: mateID, Role: "member"})
+
+	_ = queries.CreateRefreshToken(ctx, db.CreateRefreshTokenParams{
+		UserID: meID, TokenHash: "session", ExpiresAt: time.Now().Add(time.Hour),
+	})
+
+	r := chi.NewRouter()
+	r.Get("/me", userHandlers.GetMe)
+	r.Patch("/me", userHandlers.UpdateMe)
+	r.Get("/users/{id}", userHandlers.GetUser)
+
+	do := func(method, url string, body []byte, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req = req.WithContext(id_helper.WithUserID(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rrMe := do(http.MethodGet, "/me", nil, meID)
+	var me map[string]interface{}
+	json.NewDecoder(rrMe.Body).Decode(&me)
+	if rrMe.Code != http.StatusOK || me["email"] != "profile@example.com" || me["display_name"] != nil {
+		t.Fatalf("unexpected profile: %d %v", rrMe.Code, me)
+	}
+	if _, leaked := me["PasswordHash"]; leaked {
+		t.Errorf("expected password hash to stay private")
+	}
+
+	rrPatch := do(http.MethodPatch, "/me", []byte(`{"display_name": "  Egor  "}`), meID)
+	json.NewDecoder(rrPatch.Body).Decode(&me)
+	if rrPatch.Code != http.StatusOK || me["display_name"] != "Egor" {
+		t.Errorf("expected trimmed display name, got %d %v", rrPatch.Code, me)
+	}
+
+	mateURL := "/users/" + strconv.FormatInt(meID, 10)
+	rrMate := do(http.MethodGet, mateURL, nil, mateID)
+	var seen map[string]interface{}
+	json.NewDecoder(rrMate.Body).Decode(&seen)
+	if rrMate.Code != http.StatusOK || seen["display_name"] != "Egor" {
+		t.Errorf("expected teammate to see the profile, got %d %v", rrMate.Code, seen)
+	}
+	if _, ok := seen["is_admin"]; ok {
+		t.Errorf("expected is_admin to be shown only to the user themselves")
+	}
+	if rr := do(http.MethodGet, mateURL, nil, strangerID); rr.Code != http.StatusNotFound {
+		t.Errorf("expected non-teammate to get 404, got %d", rr.Code)
+	}
+
+	if rr := do(http.MethodPatch, "/me", []byte(`{"new_password": "newpassword"}`), meID); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected missing current password to get 422, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPatch, "/me", []byte(`{"current_password": "wrong", "new_password": "newpassword"}`), meID); rr.Code != http.StatusForbidden {
+		t.Errorf("expected wrong current password to get 403, got %d", rr.Code)
+	}
+
+	rrPassword := do(http.MethodPatch, "/me", []byte(`{"current_password": "oldpassword", "new_password": "newpassword"}`), meID)
+	if rrPassword.Code != http.StatusOK {
+		t.Fatalf("expected password change, got %d: %s", rrPassword.Code, rrPassword.Body.String())
+	}
+
+	user, _ := queries.GetUserByID(ctx, meID)
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("newpassword")) != nil {
+		t.Errorf("expected the new password to be stored")
+	}
+	if !user.DisplayName.Valid {
+		t.Errorf("expected the display name to survive a password change")
+	}
+	token, _ := queries.GetRefreshTokenByHash(ctx, "session")
+	if !token.RevokedAt.Valid {
+		t.Errorf("expected refresh tokens to be revoked after a password change")
+	}
+
+	rrClear := do(http.MethodPatch, "/me", []byte(`{"display_name": null}`), meID)
+	json.NewDecoder(rrClear.Body).Decode(&me)
+	if me["display_name"] != nil {
+		t.Errorf("expected display name to be cleared, got %v", me["display_name"])
+	}
+}
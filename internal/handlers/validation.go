@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+	id_helper "github.com/egor_lukyanovich/moon_test_application/pkg/routing"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/validation"
+)
+
+const (
+	maxTitleLength       = 255
+	maxDescriptionLength = 10000
+	maxTeamNameLength    = 255
+	maxCommentLength     = 10000
+	maxDisplayNameLength = 100
+	maxStatusLength      = 50
+)
+
+// statusName keeps statuses usable as identifiers in URLs and query strings.
+var statusName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+var memberRoles = []string{
+	string(db.TeamMembersRoleOwner),
+	string(db.TeamMembersRoleAdmin),
+	string(db.TeamMembersRoleMember),
+}
+
+var inviteRoles = []string{
+	string(db.TeamMembersRoleAdmin),
+	string(db.TeamMembersRoleMember),
+}
+
+func validateTitle(v *validation.Validator, title string) {
+	v.Required("title", title)
+	v.MaxLength("title", title, maxTitleLength)
+}
+
+// validateStatus checks only the format of a status: which statuses exist is
+// decided by the team's workflow.
+func validateStatus(v *validation.Validator, status string) {
+	validateStatusName(v, "status", status)
+}
+
+func validateStatusName(v *validation.Validator, field, name string) {
+	v.Required(field, name)
+	v.MaxLength(field, name, maxStatusLength)
+	v.Check(name == "" || statusName.MatchString(name), field,
+		"must start with a lowercase letter and contain only lowercase letters, digits and underscores")
+}
+
+func validateDescription(v *validation.Validator, description string) {
+	v.MaxLength("description", description, maxDescriptionLength)
+}
+
+func validateAssignee(ctx context.Context, v *validation.Validator, q *db.Queries, teamID int64, assignee sql.NullInt64) {
+	if !assignee.Valid {
+		return
+	}
+	v.Check(id_helper.CheckTeamRole(ctx, q, teamID, assignee.Int64), "assignee_id", "must be a member of the task's team")
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+	json_resp "github.com/egor_lukyanovich/moon_test_application/pkg/json"
+	id_helper "github.com/egor_lukyanovich/moon_test_application/pkg/routing"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/validation"
+)
+
+const maxWorkflowStatuses = 20
+
+// defaultStatuses is the workflow of a team that has not configured its own.
+var defaultStatuses = []string{"todo", "in_progress", "done"}
+
+// requiredStatuses must be part of every workflow: todo is where new tasks of a
+// team with transitions start, and statistics count tasks in done as
+// completed.
+var requiredStatuses = []string{"todo", "done"}
+
+type transition struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// workflow lists a team's statuses in board order. Without transitions a task
+// may go from any status to any other; otherwise only the listed moves are
+// allowed.
+type workflow struct {
+	Statuses    []string     `json:"statuses"`
+	Transitions []transition `json:"transitions"`
+}
+
+func loadWorkflow(ctx context.Context, q *db.Queries, teamID int64) (workflow, error) {
+	statuses, err := q.ListTeamStatuses(ctx, teamID)
+	if err != nil {
+		return workflow{}, err
+	}
+	if len(statuses) == 0 {
+		return workflow{Statuses: defaultStatuses, Transitions: []transition{}}, nil
+	}
+
+	rows, err := q.ListTeamTransitions(ctx, teamID)
+	if err != nil {
+		return workflow{}, err
+	}
+	wf := workflow{Statuses: statuses, Transitions: make([]transition, 0, len(rows))}
+	for _, row := range rows {
+		wf.Transitions = append(wf.Transitions, transition{From: row.FromStatus, To: row.ToStatus})
+	}
+	return wf, nil
+}
+
+func (wf workflow) hasStatus(status string) bool {
+	for _, s := range wf.Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (wf workflow) allows(from, to string) bool {
+	if from == to || len(wf.Transitions) == 0 {
+		return true
+	}
+	for _, t := range wf.Transitions {
+		if t.From == from && t.To == to {
+			return true
+		}
+	}
+	return false
+}
+
+// checkStatusChange validates moving a task from one status to another; from
+// is empty for a new task. Without transitions a new task may start in any
+// status; otherwise it starts in todo, so it cannot skip the listed moves.
+func (wf workflow) checkStatusChange(v *validation.Validator, from, to string) {
+	v.OneOf("status", to, wf.Statuses...)
+	if !wf.hasStatus(to) {
+		return
+	}
+	if from == "" {
+		v.Check(len(wf.Transitions) == 0 || to == "todo", "status", "new tasks must start in todo")
+		return
+	}
+	v.Check(wf.allows(from, to), "status", "cannot change from "+from+" to "+to)
+}
+
+// validate checks a workflow submitted by a team, reporting on v.
+func (wf workflow) validate(v *validation.Validator) {
+	v.Check(len(wf.Statuses) <= maxWorkflowStatuses, "statuses",
+		"must contain at most "+strconv.Itoa(maxWorkflowStatuses)+" statuses")
+
+	for i, s := range wf.Statuses {
+		validateStatusName(v, "statuses", s)
+		for _, prev := range wf.Statuses[:i] {
+			v.Check(s != prev, "statuses", "must not contain duplicates")
+		}
+	}
+	for _, s := range requiredStatuses {
+		v.Check(wf.hasStatus(s), "statuses", "must include "+strings.Join(requiredStatuses, " and "))
+	}
+
+	for i, t := range wf.Transitions {
+		v.Check(wf.hasStatus(t.From) && wf.hasStatus(t.To), "transitions", "must only use statuses of the workflow")
+		v.Check(t.From != t.To, "transitions", "must connect two different statuses")
+		for _, prev := range wf.Transitions[:i] {
+			v.Check(t != prev, "transitions", "must not contain duplicates")
+		}
+	}
+}
+
+func (h *TeamHandlers) GetWorkflow(w http.ResponseWriter, r *http.Request) {
+	userID, teamID, ok := teamRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if !id_helper.CheckTeamRole(r.Context(), h.q, teamID, userID) {
+		json_resp.RespondError(w, 403, "FORBIDDEN", "you are not a member of this team")
+		return
+	}
+
+	wf, err := loadWorkflow(r.Context(), h.q, teamID)
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to fetch workflow")
+		return
+	}
+
+	json_resp.RespondJSON(w, 200, wf)
+}
+
+// UpdateWorkflow replaces the team's statuses and transitions. A status that
+// still has tasks cannot be removed; the tasks must be moved first.
+func (h *TeamHandlers) UpdateWorkflow(w http.ResponseWriter, r *http.Request) {
+	userID, teamID, ok := teamRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if !id_helper.CheckTeamRole(r.Context(), h.q, teamID, userID, "owner", "admin") {
+		json_resp.RespondError(w, 403, "FORBIDDEN", "only owner or admin can change the workflow")
+		return
+	}
+
+	var req workflow
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json_resp.RespondError(w, 400, "BAD_REQUEST", "invalid json")
+		return
+	}
+	if req.Transitions == nil {
+		req.Transitions = []transition{}
+	}
+
+	v := validation.New()
+	req.validate(v)
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to start tx")
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.q.WithTx(tx)
+
+	// Every status change of the team's tasks takes the same lock, so no task
+	// can enter a status between the check below and the commit.
+	if _, err := qtx.LockTeam(r.Context(), teamID); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to lock team")
+		return
+	}
+
+	counts, err := qtx.CountTeamTasksByStatus(r.Context(), teamID)
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to count tasks")
+		return
+	}
+	for _, c := range counts {
+		if !req.hasStatus(c.Status) {
+			json_resp.RespondError(w, 409, "CONFLICT",
+				"status "+c.Status+" still has "+strconv.FormatInt(c.Total, 10)+" tasks; move them first")
+			return
+		}
+	}
+
+	if err := qtx.DeleteTeamTransitions(r.Context(), teamID); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to update workflow")
+		return
+	}
+	if err := qtx.DeleteTeamStatuses(r.Context(), teamID); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to update workflow")
+		return
+	}
+	for i, s := range req.Statuses {
+		err := qtx.CreateTeamStatus(r.Context(), db.CreateTeamStatusParams{TeamID: teamID, Name: s, Position: int32(i)})
+		if err != nil {
+			json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to update workflow")
+			return
+		}
+	}
+	for _, t := range req.Transitions {
+		err := qtx.CreateTeamTransition(r.Context(), db.CreateTeamTransitionParams{TeamID: teamID, FromStatus: t.From, ToStatus: t.To})
+		if err != nil {
+			json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to update workflow")
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to commit tx")
+		return
+	}
+
+	json_resp.RespondJSON(w, 200, req)
+}
+
+// checkStatusChange validates the status change of an updated task against
+// its team's workflow. It returns false only when the workflow could not be
+// read, after writing the response.
+func (h *TaskHandlers) checkStatusChange(w http.ResponseWriter, r *http.Request, v *validation.Validator, qtx *db.Queries, oldTask, newTask db.Task) bool {
+	if newTask.Status == oldTask.Status {
+		return true
+	}
+
+	wf, err := loadWorkflow(r.Context(), qtx, oldTask.TeamID)
+	if err != nil {
+		json_resp.RespondError(w, 500, "INTERNAL_ERROR", "failed to fetch workflow")
+		return false
+	}
+	wf.checkStatusChange(v, oldTask.Status, newTask.Status)
+	return true
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/egor_lukyanovich/moon_test_application/pkg/validation"
+)
+
+func TestWorkflowAllows(t *testing.T) {
+	open := workflow{Statuses: defaultStatuses}
+	if !open.allows("todo", "done") || !open.allows("done", "todo") {
+		t.Errorf("expected a workflow without transitions to allow any change")
+	}
+
+	strict := workflow{
+		Statuses:    []string{"todo", "review", "done"},
+		Transitions: []transition{{From: "todo", To: "review"}, {From: "review", To: "done"}},
+	}
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"todo", "review", true},
+		{"review", "done", true},
+		{"todo", "done", false},
+		{"done", "review", false},
+		{"review", "review", true},
+	}
+	for _, tt := range tests {
+		if got := strict.allows(tt.from, tt.to); got != tt.want {
+			t.Errorf("allows(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	v := validation.New()
+	open.checkStatusChange(v, "", "done")
+	if !v.Valid() {
+		t.Errorf("expected a new task to start in any status without transitions, got %+v", v.Errors())
+	}
+	v = validation.New()
+	strict.checkStatusChange(v, "", "todo")
+	if !v.Valid() {
+		t.Errorf("expected a new task to start in todo, got %+v", v.Errors())
+	}
+	v = validation.New()
+	strict.checkStatusChange(v, "", "review")
+	if v.Valid() {
+		t.Errorf("expected a new task to be kept out of review when transitions are set")
+	}
+	v = validation.New()
+	strict.checkStatusChange(v, "todo", "blocked")
+	if v.Valid() || v.Errors()[0].Message != "must be one of: todo, review, done" {
+		t.Errorf("expected unknown status to be reported, got %+v", v.Errors())
+	}
+}
+
+func TestWorkflowValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		wf     workflow
+		fields []string
+	}{
+		{name: "valid", wf: workflow{
+			Statuses:    []string{"todo", "in_progress", "review", "done"},
+			Transitions: []transition{{From: "todo", To: "in_progress"}, {From: "review", To: "done"}},
+		}},
+		{name: "missing done", wf: workflow{Statuses: []string{"todo", "review"}}, fields: []string{"statuses"}},
+		{name: "duplicate status", wf: workflow{Statuses: []string{"todo", "done", "todo"}}, fields: []string{"statuses"}},
+		{name: "bad name", wf: workflow{Statuses: []string{"todo", "done", "In Review"}}, fields: []string{"statuses"}},
+		{name: "unknown transition status", wf: workflow{
+			Statuses:    []string{"todo", "done"},
+			Transitions: []transition{{From: "todo", To: "review"}},
+		}, fields: []string{"transitions"}},
+		{name: "self transition", wf: workflow{
+			Statuses:    []string{"todo", "done"},
+			Transitions: []transition{{From: "done", To: "done"}},
+		}, fields: []string{"transitions"}},
+		{name: "duplicate transition", wf: workflow{
+			Statuses:    []string{"todo", "done"},
+			Transitions: []transition{{From: "todo", To: "done"}, {From: "todo", To: "done"}},
+		}, fields: []string{"transitions"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validation.New()
+			tt.wf.validate(v)
+
+			if len(v.Errors()) != len(tt.fields) {
+				t.Fatalf("expected errors for %v, got %+v", tt.fields, v.Errors())
+			}
+			for i, field := range tt.fields {
+				if v.Errors()[i].Field != field {
+					t.Errorf("expected error for %s, got %+v", field, v.Errors()[i])
+				}
+			}
+		})
+	}
+}
@@ -1,8 +1,14 @@
 package models
 
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 type ErrorResponse struct {
 	Error struct {
-		Code string `json:"code"`
-		Text string `json:"text"`
+		Code    string       `json:"code"`
+		Text    string       `json:"text"`
+		Details []FieldError `json:"details,omitempty"`
 	} `json:"error"`
 }
@@ -20,6 +20,15 @@ func RespondError(w http.ResponseWriter, code int, errCode, msg string) {
 	RespondJSON(w, code, res)
 }
 
+func RespondValidationError(w http.ResponseWriter, details []models.FieldError) {
+	res := models.ErrorResponse{}
+	res.Error.Code = "VALIDATION_ERROR"
+	res.Error.Text = "request validation failed"
+	res.Error.Details = details
+
+	RespondJSON(w, http.StatusUnprocessableEntity, res)
+}
+
 func RespondJSON(w http.ResponseWriter, code int, payload interface{}) {
 	data, err := json.Marshal(payload)
 	if err != nil {
@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// LogMailer writes every message to w instead of delivering it. It is meant
+// for local development and tests, where the log or file can be inspected.
+type LogMailer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+}
+
+func NewLogMailer(w io.Writer, from string) *LogMailer {
+	return &LogMailer{w: w, from: from}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	data, err := format(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := io.WriteString(m.w, "----- mail -----\r\n"); err != nil {
+		return err
+	}
+	_, err = m.w.Write(append(data, "\r\n"...))
+	return err
+}
@@ -0,0 +1,102 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultFrom = "no-reply@localhost"
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+var errHeaderInjection = errors.New("mail: header value contains a line break")
+
+// format renders msg as an RFC 5322 message. Header values with line breaks
+// are rejected so user input cannot inject extra headers or recipients.
+func format(from string, msg Message, now time.Time) ([]byte, error) {
+	for _, v := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, errHeaderInjection
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String()), nil
+}
+
+// ErrNoMailer is returned by FromEnv when no way of delivering mail is
+// configured. There is deliberately no silent fallback: the log mailers write
+// password reset tokens in plain text.
+var ErrNoMailer = errors.New("no mailer configured: set SMTP_ADDR, or MAIL_BACKEND=log for local development")
+
+// FromEnv picks a mailer from MAIL_BACKEND:
+//   - "smtp" sends real mail to SMTP_ADDR (host:port) with optional
+//     SMTP_USERNAME/SMTP_PASSWORD;
+//   - "log" appends messages to MAIL_LOG_FILE, or writes them to the standard
+//     logger when it is unset. Meant for local development only;
+//   - when empty, SMTP is used if SMTP_ADDR is set. Otherwise the function
+//     fails, unless APP_ENV=test, which gets the log mailer.
+//
+// MAIL_FROM sets the sender address for every mailer.
+func FromEnv() (Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = defaultFrom
+	}
+
+	backend := os.Getenv("MAIL_BACKEND")
+	if backend == "" {
+		switch {
+		case os.Getenv("SMTP_ADDR") != "":
+			backend = "smtp"
+		case os.Getenv("APP_ENV") == "test":
+			backend = "log"
+		default:
+			return nil, ErrNoMailer
+		}
+	}
+
+	switch backend {
+	case "smtp":
+		addr := os.Getenv("SMTP_ADDR")
+		if addr == "" {
+			return nil, errors.New("MAIL_BACKEND=smtp requires SMTP_ADDR")
+		}
+		return NewSMTPMailer(addr, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from), nil
+	case "log":
+		if path := os.Getenv("MAIL_LOG_FILE"); path != "" {
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+			if err != nil {
+				return nil, fmt.Errorf("open MAIL_LOG_FILE: %w", err)
+			}
+			return NewLogMailer(f, from), nil
+		}
+		return NewLogMailer(log.Writer(), from), nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_BACKEND %q", backend)
+	}
+}
@@ -0,0 +1,147 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewLogMailer(&buf, "app@example.com")
+
+	err := m.Send(context.Background(), Message{To: "user@example.com", Subject: "Сброс пароля", Body: "line 1\nline 2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"From: app@example.com\r\n", "To: user@example.com\r\n", "Subject: =?utf-8?q?", "\r\n\r\nline 1\r\nline 2"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	err = m.Send(context.Background(), Message{To: "user@example.com\r\nBcc: victim@example.com", Subject: "Hi"})
+	if err != errHeaderInjection {
+		t.Errorf("expected header injection to be rejected, got %v", err)
+	}
+}
+
+// fakeSMTP accepts a single session and returns the DATA payload.
+func fakeSMTP(t *testing.T) (string, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	data := make(chan string, 1)
+	go func() {
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"):
+				reply("250 localhost")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var body strings.Builder
+				for {
+					l, _ := r.ReadString('\n')
+					if l == ".\r\n" {
+						break
+					}
+					body.WriteString(l)
+				}
+				data <- body.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	return ln.Addr().String(), data
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, data := fakeSMTP(t)
+	m := NewSMTPMailer(addr, "", "", "app@example.com")
+
+	err := m.Send(context.Background(), Message{To: "user@example.com", Subject: "Reset", Body: "token: abc"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	body := <-data
+	if !strings.Contains(body, "To: user@example.com\r\n") || !strings.Contains(body, "token: abc") {
+		t.Errorf("unexpected message:\n%s", body)
+	}
+}
+
+var mailEnv = []string{"APP_ENV", "MAIL_BACKEND", "SMTP_ADDR", "MAIL_LOG_FILE"}
+
+func TestFromEnv(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "mail.log")
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    string
+		wantErr bool
+	}{
+		{name: "nothing configured", env: map[string]string{}, wantErr: true},
+		{name: "log file without explicit backend", env: map[string]string{"MAIL_LOG_FILE": logFile}, wantErr: true},
+		{name: "test mode", env: map[string]string{"APP_ENV": "test"}, want: "log"},
+		{name: "explicit log backend", env: map[string]string{"MAIL_BACKEND": "log", "MAIL_LOG_FILE": logFile}, want: "log"},
+		{name: "smtp address", env: map[string]string{"SMTP_ADDR": "smtp.example.com:587"}, want: "smtp"},
+		{name: "smtp backend without address", env: map[string]string{"MAIL_BACKEND": "smtp"}, wantErr: true},
+		{name: "unknown backend", env: map[string]string{"MAIL_BACKEND": "pigeon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range mailEnv {
+				t.Setenv(name, tt.env[name])
+			}
+
+			m, err := FromEnv()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %T", m)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := "log"
+			if _, ok := m.(*SMTPMailer); ok {
+				got = "smtp"
+			}
+			if got != tt.want {
+				t.Errorf("expected %s mailer, got %T", tt.want, m)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// Rate describes a token bucket holding up to Burst tokens that refills at
+// Burst tokens per Period.
+type Rate struct {
+	Burst  int
+	Period time.Duration
+}
+
+// tokenTime is how long the bucket takes to refill n tokens.
+func (r Rate) tokenTime(n float64) time.Duration {
+	return time.Duration(n * float64(r.Period) / float64(r.Burst))
+}
+
+// Buckets hands out tokens from per-key token buckets. Unlike a sliding
+// window, a bucket allows short bursts while keeping the long-term average.
+type Buckets interface {
+	// Take removes one token from the bucket under key if it has one.
+	Take(ctx context.Context, key string, rate Rate) (Decision, error)
+}
+
+// bucketDecision turns the tokens left in a bucket into a Decision.
+func bucketDecision(rate Rate, tokens float64, allowed bool) Decision {
+	d := Decision{
+		Allowed:    allowed,
+		Remaining:  int(tokens),
+		ResetAfter: rate.tokenTime(float64(rate.Burst) - tokens),
+	}
+	if !allowed {
+		d.RetryAfter = rate.tokenTime(1 - tokens)
+	}
+	return d
+}
+
+// FallbackBuckets serves tokens from primary and switches to local buckets
+// while primary fails, so quotas keep working per instance during a Redis
+// outage instead of being dropped.
+type FallbackBuckets struct {
+	primary  Buckets
+	local    Buckets
+	degraded atomic.Bool
+}
+
+func NewFallbackBuckets(primary, local Buckets) *FallbackBuckets {
+	return &FallbackBuckets{primary: primary, local: local}
+}
+
+func (f *FallbackBuckets) Take(ctx context.Context, key string, rate Rate) (Decision, error) {
+	d, err := f.primary.Take(ctx, key, rate)
+	if err == nil {
+		if f.degraded.CompareAndSwap(true, false) {
+			log.Println("rate limit store recovered")
+		}
+		return d, nil
+	}
+
+	if f.degraded.CompareAndSwap(false, true) {
+		log.Println("rate limit store unavailable, using local buckets:", err)
+	}
+	return f.local.Take(ctx, key, rate)
+}
@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	tc_redis "github.com/testcontainers/testcontainers-go/modules/redis"
+)
+
+// testTokenBucket checks the behaviour both bucket stores must share.
+func testTokenBucket(t *testing.T, b Buckets, clock *fakeClock) {
+	ctx := context.Background()
+	rate := Rate{Burst: 3, Period: 30 * time.Second}
+
+	for i := 0; i < 3; i++ {
+		d, err := b.Take(ctx, "k", rate)
+		if err != nil {
+			t.Fatalf("take: %v", err)
+		}
+		if !d.Allowed || d.Remaining != 2-i {
+			t.Fatalf("take %d: expected allowed with %d remaining, got %+v", i, 2-i, d)
+		}
+	}
+
+	d, err := b.Take(ctx, "k", rate)
+	if err != nil {
+		t.Fatalf("take: %v", err)
+	}
+	if d.Allowed || d.RetryAfter != 10*time.Second || d.ResetAfter != 30*time.Second {
+		t.Fatalf("expected empty bucket refilling one token per 10s, got %+v", d)
+	}
+
+	if d, _ := b.Take(ctx, "other", rate); !d.Allowed {
+		t.Errorf("expected keys to have separate buckets")
+	}
+
+	// Half a token is not enough; the rejected take must not cost anything.
+	clock.advance(5 * time.Second)
+	if d, _ := b.Take(ctx, "k", rate); d.Allowed || d.RetryAfter != 5*time.Second {
+		t.Fatalf("expected rejection for 5 more seconds, got %+v", d)
+	}
+	clock.advance(5 * time.Second)
+	if d, _ := b.Take(ctx, "k", rate); !d.Allowed || d.Remaining != 0 {
+		t.Fatalf("expected the refilled token to be taken, got %+v", d)
+	}
+
+	// Refill is capped at Burst.
+	clock.advance(time.Hour)
+	if d, _ := b.Take(ctx, "k", rate); !d.Allowed || d.Remaining != 2 {
+		t.Errorf("expected a full bucket after a long pause, got %+v", d)
+	}
+}
+
+func TestMemoryTokenBucket(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	b := NewMemoryBuckets()
+	b.now = clock.now
+
+	testTokenBucket(t, b, clock)
+}
+
+func TestRedisTokenBucket(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, err := tc_redis.Run(ctx, "redis:7")
+	if err != nil {
+		t.Fatalf("failed to start redis container: %v", err)
+	}
+	defer redisContainer.Terminate(ctx)
+
+	uri, err := redisContainer.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("failed to get redis uri: %v", err)
+	}
+	rdb := goredis.NewClient(&goredis.Options{Addr: uri[8:]})
+	defer rdb.Close()
+
+	clock := &fakeClock{t: time.Now()}
+	b := NewRedisBuckets(rdb)
+	b.now = clock.now
+
+	testTokenBucket(t, b, clock)
+}
+
+type failingBuckets struct {
+	err error
+}
+
+func (f *failingBuckets) Take(ctx context.Context, key string, rate Rate) (Decision, error) {
+	if f.err != nil {
+		return Decision{}, f.err
+	}
+	return Decision{Allowed: true, Remaining: rate.Burst - 1}, nil
+}
+
+func TestFallbackBuckets(t *testing.T) {
+	ctx := context.Background()
+	rate := Rate{Burst: 1, Period: time.Minute}
+	primary := &failingBuckets{err: errors.New("connection refused")}
+	b := NewFallbackBuckets(primary, NewMemoryBuckets())
+
+	if d, err := b.Take(ctx, "k", rate); err != nil || !d.Allowed {
+		t.Fatalf("expected local bucket to serve the first take, got %+v, %v", d, err)
+	}
+	if d, err := b.Take(ctx, "k", rate); err != nil || d.Allowed {
+		t.Fatalf("expected local bucket to enforce the quota, got %+v, %v", d, err)
+	}
+
+	primary.err = nil
+	if d, _ := b.Take(ctx, "k", rate); !d.Allowed {
+		t.Errorf("expected primary to be used again once it recovers")
+	}
+}
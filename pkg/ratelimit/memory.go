@@ -0,0 +1,163 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+// Memory keeps hits in process memory. Limits are not shared between
+// instances, so it suits tests and single-instance deployments without Redis.
+type Memory struct {
+	mu        sync.Mutex
+	hits      map[string][]time.Time
+	windows   map[string]time.Duration
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		hits:    map[string][]time.Time{},
+		windows: map[string]time.Duration{},
+		now:     time.Now,
+	}
+}
+
+// prune drops hits that left the window and returns what is left.
+func (m *Memory) prune(key string, window time.Duration, now time.Time) []time.Time {
+	hits := m.hits[key]
+	i := 0
+	for i < len(hits) && !hits[i].After(now.Add(-window)) {
+		i++
+	}
+	hits = hits[i:]
+
+	if len(hits) == 0 {
+		delete(m.hits, key)
+		delete(m.windows, key)
+	} else {
+		m.hits[key] = hits
+		m.windows[key] = window
+	}
+	return hits
+}
+
+// sweep prunes keys that are no longer hit, so memory does not grow with
+// every IP or email ever seen.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for key, window := range m.windows {
+		m.prune(key, window, now)
+	}
+}
+
+func (m *Memory) Allow(ctx context.Context, key string, limit int, window time.Duration) (Decision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+	hits := m.prune(key, window, now)
+
+	var oldest time.Time
+	if len(hits) > 0 {
+		oldest = hits[0]
+	}
+	d := decide(len(hits), limit, oldest, window, now)
+
+	if d.Allowed {
+		m.hits[key] = append(hits, now)
+		m.windows[key] = window
+		d.Remaining--
+	}
+	return d, nil
+}
+
+func (m *Memory) Undo(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hits := m.hits[key]
+	if len(hits) <= 1 {
+		delete(m.hits, key)
+		delete(m.windows, key)
+		return nil
+	}
+	m.hits[key] = hits[:len(hits)-1]
+	return nil
+}
+
+func (m *Memory) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.hits, key)
+	delete(m.windows, key)
+	return nil
+}
+
+type bucketState struct {
+	tokens float64
+	at     time.Time
+	rate   Rate
+}
+
+// MemoryBuckets keeps token buckets in process memory.
+type MemoryBuckets struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucketState
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryBuckets() *MemoryBuckets {
+	return &MemoryBuckets{buckets: map[string]*bucketState{}, now: time.Now}
+}
+
+// sweep drops buckets that have refilled completely: they are no different
+// from a bucket that was never used.
+func (m *MemoryBuckets) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for key, b := range m.buckets {
+		if now.Sub(b.at) >= b.rate.Period {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+func (m *MemoryBuckets) Take(ctx context.Context, key string, rate Rate) (Decision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucketState{tokens: float64(rate.Burst), at: now}
+		m.buckets[key] = b
+	}
+	b.rate = rate
+
+	if elapsed := now.Sub(b.at); elapsed > 0 {
+		b.tokens += float64(elapsed) * float64(rate.Burst) / float64(rate.Period)
+		if b.tokens > float64(rate.Burst) {
+			b.tokens = float64(rate.Burst)
+		}
+		b.at = now
+	}
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return bucketDecision(rate, b.tokens, allowed), nil
+}
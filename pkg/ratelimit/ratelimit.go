@@ -0,0 +1,71 @@
+// Package ratelimit implements sliding-window rate limiting over a shared
+// Redis backend or process-local memory.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Decision is the outcome of a rate limit check.
+type Decision struct {
+	Allowed bool
+	// Remaining is how many more hits fit into the current window.
+	Remaining int
+	// RetryAfter is how long until the next hit would be allowed; zero when
+	// the hit is allowed.
+	RetryAfter time.Duration
+	// ResetAfter is how long until a token bucket is full again. Sliding
+	// windows leave it zero.
+	ResetAfter time.Duration
+}
+
+// Limiter counts hits per key over a sliding window: a hit is allowed when
+// fewer than limit hits were recorded during the last window.
+type Limiter interface {
+	// Allow records a hit under key if it is allowed. Rejected hits are not
+	// recorded, so a client that keeps retrying is let in once the window moves.
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (Decision, error)
+	// Undo forgets the most recent hit recorded under key, for a hit that
+	// turned out not to count.
+	Undo(ctx context.Context, key string) error
+	// Reset forgets every hit recorded under key.
+	Reset(ctx context.Context, key string) error
+}
+
+func decide(count, limit int, oldest time.Time, window time.Duration, now time.Time) Decision {
+	if count < limit {
+		return Decision{Allowed: true, Remaining: limit - count}
+	}
+	return Decision{RetryAfter: oldest.Add(window).Sub(now)}
+}
+
+// FromEnv picks the backend from RATE_LIMIT_BACKEND: "redis" (the default) or
+// "memory" for deployments that run a single instance without Redis.
+func FromEnv(rdb *redis.Client) (Limiter, error) {
+	switch backend := os.Getenv("RATE_LIMIT_BACKEND"); backend {
+	case "", "redis":
+		return NewRedis(rdb), nil
+	case "memory":
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_BACKEND %q", backend)
+	}
+}
+
+// BucketsFromEnv is FromEnv for token buckets. The Redis backend falls back to
+// local buckets while Redis is unreachable.
+func BucketsFromEnv(rdb *redis.Client) (Buckets, error) {
+	switch backend := os.Getenv("RATE_LIMIT_BACKEND"); backend {
+	case "", "redis":
+		return NewFallbackBuckets(NewRedisBuckets(rdb), NewMemoryBuckets()), nil
+	case "memory":
+		return NewMemoryBuckets(), nil
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_BACKEND %q", backend)
+	}
+}
@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	tc_redis "github.com/testcontainers/testcontainers-go/modules/redis"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// testSlidingWindow checks the behaviour both backends must share.
+func testSlidingWindow(t *testing.T, l Limiter, clock *fakeClock) {
+	ctx := context.Background()
+	window := time.Minute
+
+	for i := 0; i < 3; i++ {
+		d, err := l.Allow(ctx, "k", 3, window)
+		if err != nil {
+			t.Fatalf("allow: %v", err)
+		}
+		if !d.Allowed || d.Remaining != 2-i {
+			t.Fatalf("hit %d: expected allowed with %d remaining, got %+v", i, 2-i, d)
+		}
+		clock.advance(10 * time.Second)
+	}
+
+	d, err := l.Allow(ctx, "k", 3, window)
+	if err != nil {
+		t.Fatalf("allow: %v", err)
+	}
+	if d.Allowed || d.RetryAfter != 30*time.Second {
+		t.Fatalf("expected rejection with 30s retry, got %+v", d)
+	}
+
+	if d, _ := l.Allow(ctx, "other", 3, window); !d.Allowed {
+		t.Errorf("expected keys to be limited independently")
+	}
+
+	// Rejected hits are not recorded: once the first hit leaves the window
+	// exactly one slot frees up.
+	clock.advance(30 * time.Second)
+	if d, _ := l.Allow(ctx, "k", 3, window); !d.Allowed || d.Remaining != 0 {
+		t.Fatalf("expected one free slot after the window moved, got %+v", d)
+	}
+	if err := l.Undo(ctx, "k"); err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	if d, _ := l.Allow(ctx, "k", 3, window); !d.Allowed {
+		t.Fatalf("expected undo to free the slot again, got %+v", d)
+	}
+	if d, _ := l.Allow(ctx, "k", 3, window); d.Allowed || d.RetryAfter != 10*time.Second {
+		t.Fatalf("expected rejection until the second hit expires, got %+v", d)
+	}
+
+	if err := l.Reset(ctx, "k"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if d, _ := l.Allow(ctx, "k", 3, window); !d.Allowed || d.Remaining != 2 {
+		t.Errorf("expected reset to clear the key, got %+v", d)
+	}
+}
+
+func TestMemorySlidingWindow(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	m := NewMemory()
+	m.now = clock.now
+
+	testSlidingWindow(t, m, clock)
+}
+
+func TestMemorySweepsIdleKeys(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	m := NewMemory()
+	m.now = clock.now
+
+	for _, key := range []string{"a", "b", "c"} {
+		m.Allow(context.Background(), key, 1, time.Second)
+	}
+	clock.advance(2 * sweepInterval)
+	m.Allow(context.Background(), "d", 1, time.Second)
+
+	if len(m.hits) != 1 || len(m.windows) != 1 {
+		t.Errorf("expected only the fresh key to be kept, got %v", m.hits)
+	}
+}
+
+func TestRedisSlidingWindow(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, err := tc_redis.Run(ctx, "redis:7")
+	if err != nil {
+		t.Fatalf("failed to start redis container: %v", err)
+	}
+	defer redisContainer.Terminate(ctx)
+
+	uri, err := redisContainer.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("failed to get redis uri: %v", err)
+	}
+	rdb := goredis.NewClient(&goredis.Options{Addr: uri[8:]})
+	defer rdb.Close()
+
+	// Keys expire by the server clock, which the fake clock does not move;
+	// the test finishes long before a real minute passes.
+	clock := &fakeClock{t: time.Now()}
+	l := NewRedis(rdb)
+	l.now = clock.now
+
+	testSlidingWindow(t, l, clock)
+}
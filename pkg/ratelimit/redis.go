@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindow keeps one sorted set of hit timestamps (ms) per key. Pruning,
+// counting and recording happen in one script, so concurrent requests cannot
+// both take the last free slot.
+var slidingWindow = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	redis.call('PEXPIRE', key, window)
+end
+
+local oldest = now
+local first = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if first[2] then
+	oldest = tonumber(first[2])
+end
+return {count, oldest}
+`)
+
+type Redis struct {
+	rdb    *redis.Client
+	prefix string
+	now    func() time.Time
+}
+
+func NewRedis(rdb *redis.Client) *Redis {
+	return &Redis{rdb: rdb, prefix: "ratelimit:", now: time.Now}
+}
+
+func (l *Redis) Allow(ctx context.Context, key string, limit int, window time.Duration) (Decision, error) {
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return Decision{}, err
+	}
+
+	now := l.now()
+	res, err := slidingWindow.Run(ctx, l.rdb, []string{l.prefix + key},
+		now.UnixMilli(), window.Milliseconds(), limit, hex.EncodeToString(nonce)).Int64Slice()
+	if err != nil {
+		return Decision{}, err
+	}
+
+	d := decide(int(res[0]), limit, time.UnixMilli(res[1]), window, now)
+	if d.Allowed {
+		d.Remaining--
+	}
+	return d, nil
+}
+
+func (l *Redis) Undo(ctx context.Context, key string) error {
+	return l.rdb.ZPopMax(ctx, l.prefix+key).Err()
+}
+
+func (l *Redis) Reset(ctx context.Context, key string) error {
+	return l.rdb.Del(ctx, l.prefix+key).Err()
+}
+
+// tokenBucket stores the tokens left and the time of the last refill (ms) in
+// a hash. The key expires once the bucket would be full anyway. Tokens are
+// fractional, so they travel as strings: Lua numbers returned to Redis are
+// truncated to integers.
+var tokenBucket = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', key, 'tokens', 'at')
+local tokens = tonumber(state[1])
+local at = tonumber(state[2])
+if tokens == nil or at == nil then
+	tokens = burst
+	at = now
+end
+
+if now > at then
+	tokens = math.min(burst, tokens + (now - at) * burst / period)
+	at = now
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', key, 'tokens', tostring(tokens), 'at', at)
+redis.call('PEXPIRE', key, period)
+return {allowed, tostring(tokens)}
+`)
+
+type RedisBuckets struct {
+	rdb    *redis.Client
+	prefix string
+	now    func() time.Time
+}
+
+func NewRedisBuckets(rdb *redis.Client) *RedisBuckets {
+	return &RedisBuckets{rdb: rdb, prefix: "ratelimit:bucket:", now: time.Now}
+}
+
+func (b *RedisBuckets) Take(ctx context.Context, key string, rate Rate) (Decision, error) {
+	res, err := tokenBucket.Run(ctx, b.rdb, []string{b.prefix + key},
+		b.now().UnixMilli(), rate.Burst, rate.Period.Milliseconds()).Slice()
+	if err != nil {
+		return Decision{}, err
+	}
+	if len(res) != 2 {
+		return Decision{}, fmt.Errorf("unexpected token bucket reply %v", res)
+	}
+
+	allowed, _ := res[0].(int64)
+	raw, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return Decision{}, fmt.Errorf("parse token bucket reply: %w", err)
+	}
+
+	return bucketDecision(rate, tokens, allowed == 1), nil
+}
@@ -2,34 +2,36 @@ package routing
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
+	"io"
+	"log"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/egor_lukyanovich/moon_test_application/internal/db"
 	json_resp "github.com/egor_lukyanovich/moon_test_application/pkg/json"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/validation"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type contextKey string
 
-const userIDKey contextKey = "userID"
+const (
+	userIDKey      contextKey = "userID"
+	accessTokenKey contextKey = "accessToken"
+)
 
 type authRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
-func getJWTKey() []byte {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return []byte("test_secret_key_123")
-	}
-	return []byte(secret)
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 func GetUserIDHelper(ctx context.Context) (int64, bool) {
@@ -64,7 +66,21 @@ func CheckTeamRole(ctx context.Context, q *db.Queries, teamID, userID int64, all
 	return false
 }
 
-func AuthMiddleware(next http.Handler) http.Handler {
+func IsSystemAdmin(ctx context.Context, q *db.Queries, userID int64) bool {
+	user, err := q.GetUserByID(ctx, userID)
+	if err != nil {
+		return false
+	}
+	return user.IsAdmin
+}
+
+func AuthMiddleware(keys *KeySet, denylist TokenDenylist) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return authMiddleware(keys, denylist, next)
+	}
+}
+
+func authMiddleware(keys *KeySet, denylist TokenDenylist, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
@@ -80,12 +96,7 @@ func AuthMiddleware(next http.Handler) http.Handler {
 
 		tokenString := parts[1]
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.New("unexpected signing method")
-			}
-			return getJWTKey(), nil
-		})
+		token, err := jwt.Parse(tokenString, keys.keyFunc)
 
 		if err != nil || !token.Valid {
 			json_resp.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid or expired token")
@@ -104,17 +115,44 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		jti, ok := claims["jti"].(string)
+		if !ok || jti == "" {
+			json_resp.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid token ID")
+			return
+		}
+
+		revoked, err := denylist.IsRevoked(r.Context(), jti)
+		if err != nil {
+			log.Println("token denylist lookup failed:", err)
+			json_resp.RespondError(w, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "cannot verify token right now")
+			return
+		}
+		if revoked {
+			json_resp.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "token has been revoked")
+			return
+		}
+
+		expiresAt, err := claims.GetExpirationTime()
+		if err != nil || expiresAt == nil {
+			json_resp.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid token expiry")
+			return
+		}
+
 		ctx := WithUserID(r.Context(), int64(userIDFloat))
+		ctx = context.WithValue(ctx, accessTokenKey, accessToken{ID: jti, ExpiresAt: expiresAt.Time})
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
 type AuthHandlers struct {
-	q *db.Queries
+	q        *db.Queries
+	db       *sql.DB
+	keys     *KeySet
+	denylist TokenDenylist
 }
 
-func NewAuthHandlers(q *db.Queries) *AuthHandlers {
-	return &AuthHandlers{q: q}
+func NewAuthHandlers(q *db.Queries, database *sql.DB, keys *KeySet, denylist TokenDenylist) *AuthHandlers {
+	return &AuthHandlers{q: q, db: database, keys: keys, denylist: denylist}
 }
 
 func (h *AuthHandlers) Register(w http.ResponseWriter, r *http.Request) {
@@ -124,13 +162,29 @@ func (h *AuthHandlers) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	v := validation.New()
+	v.Email("email", req.Email)
+	v.Password("password", req.Password)
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to hash password")
 		return
 	}
 
-	res, err := h.q.CreateUser(r.Context(), db.CreateUserParams{
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to start tx")
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.q.WithTx(tx)
+
+	res, err := qtx.CreateUser(r.Context(), db.CreateUserParams{
 		Email:        req.Email,
 		PasswordHash: string(hashedPassword),
 	})
@@ -141,6 +195,21 @@ func (h *AuthHandlers) Register(w http.ResponseWriter, r *http.Request) {
 
 	userID, _ := res.LastInsertId()
 
+	// Invitations sent to this email before the account existed now belong to it.
+	err = qtx.AttachInvitationsToUser(r.Context(), db.AttachInvitationsToUserParams{
+		UserID: sql.NullInt64{Int64: userID, Valid: true},
+		Email:  req.Email,
+	})
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to attach invitations")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to commit tx")
+		return
+	}
+
 	json_resp.RespondJSON(w, http.StatusCreated, map[string]interface{}{
 		"id":      userID,
 		"message": "user registered successfully",
@@ -154,6 +223,14 @@ func (h *AuthHandlers) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	v := validation.New()
+	v.Required("email", req.Email)
+	v.Required("password", req.Password)
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
 	user, err := h.q.GetUserByEmail(r.Context(), req.Email)
 	if err != nil {
 		json_resp.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid email or password")
@@ -166,19 +243,110 @@ func (h *AuthHandlers) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": user.ID,
-		"exp": time.Now().Add(72 * time.Hour).Unix(),
-		"iat": time.Now().Unix(),
-	})
+	tokens, err := issueTokens(r.Context(), h.q, h.keys, user.ID)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to generate token")
+		return
+	}
+
+	json_resp.RespondJSON(w, http.StatusOK, tokens)
+}
 
-	tokenString, err := token.SignedString(getJWTKey())
+func (h *AuthHandlers) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json_resp.RespondError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json body")
+		return
+	}
+
+	v := validation.New()
+	v.Required("refresh_token", req.RefreshToken)
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to start tx")
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.q.WithTx(tx)
+
+	stored, err := qtx.GetRefreshTokenByHash(r.Context(), hashToken(req.RefreshToken))
+	if err != nil {
+		json_resp.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid refresh token")
+		return
+	}
+
+	if stored.RevokedAt.Valid {
+		// A rotated token came back: treat it as stolen and end every session of the user.
+		if err := qtx.RevokeUserRefreshTokens(r.Context(), stored.UserID); err == nil {
+			tx.Commit()
+		}
+		json_resp.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "refresh token has been revoked")
+		return
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		json_resp.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "refresh token expired")
+		return
+	}
+
+	revoked, err := qtx.RevokeRefreshToken(r.Context(), stored.ID)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to rotate refresh token")
+		return
+	}
+	if revoked == 0 {
+		json_resp.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "refresh token has been revoked")
+		return
+	}
+
+	tokens, err := issueTokens(r.Context(), qtx, h.keys, stored.UserID)
 	if err != nil {
 		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to generate token")
 		return
 	}
 
-	json_resp.RespondJSON(w, http.StatusOK, map[string]string{
-		"token": tokenString,
-	})
+	if err := tx.Commit(); err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to commit tx")
+		return
+	}
+
+	json_resp.RespondJSON(w, http.StatusOK, tokens)
+}
+
+func (h *AuthHandlers) Logout(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserIDHelper(r.Context())
+	if !ok {
+		json_resp.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "unauthorized")
+		return
+	}
+
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		json_resp.RespondError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json body")
+		return
+	}
+
+	if token, ok := r.Context().Value(accessTokenKey).(accessToken); ok {
+		if err := h.denylist.Revoke(r.Context(), token.ID, time.Until(token.ExpiresAt)); err != nil {
+			json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to revoke access token")
+			return
+		}
+	}
+
+	if req.RefreshToken != "" {
+		stored, err := h.q.GetRefreshTokenByHash(r.Context(), hashToken(req.RefreshToken))
+		if err == nil && stored.UserID == userID {
+			if _, err := h.q.RevokeRefreshToken(r.Context(), stored.ID); err != nil {
+				json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to revoke refresh token")
+				return
+			}
+		}
+	}
+
+	json_resp.RespondJSON(w, http.StatusOK, map[string]string{"status": "logged out"})
 }
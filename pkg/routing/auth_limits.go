@@ -0,0 +1,215 @@
+package routing
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	json_resp "github.com/egor_lukyanovich/moon_test_application/pkg/json"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/ratelimit"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// maxPeekBody bounds how much of a request body is buffered to find the email
+// or password.
+const maxPeekBody = 64 << 10
+
+// AuthLimits configures throttling of the public auth endpoints.
+type AuthLimits struct {
+	// PerIP and PerEmail hits are allowed within Window.
+	PerIP    int
+	PerEmail int
+	Window   time.Duration
+	// An account is locked once MaxFailures logins fail within LockoutWindow.
+	MaxFailures   int
+	LockoutWindow time.Duration
+}
+
+func DefaultAuthLimits() AuthLimits {
+	return AuthLimits{
+		PerIP:         20,
+		PerEmail:      5,
+		Window:        time.Minute,
+		MaxFailures:   5,
+		LockoutWindow: 15 * time.Minute,
+	}
+}
+
+// AuthLimiter provides the throttling middlewares of the auth endpoints. When
+// the limiter backend fails, requests are let through: losing the throttling is
+// preferred over locking everyone out of the API.
+type AuthLimiter struct {
+	limiter ratelimit.Limiter
+	limits  AuthLimits
+}
+
+func NewAuthLimiter(limiter ratelimit.Limiter, limits AuthLimits) *AuthLimiter {
+	return &AuthLimiter{limiter: limiter, limits: limits}
+}
+
+// RateLimit throttles requests per client IP and per email found in the JSON
+// body.
+func (l *AuthLimiter) RateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.allowHit(w, r, "auth:ip:"+clientIP(r), l.limits.PerIP) {
+			return
+		}
+
+		if email := peekEmail(r); email != "" {
+			if !l.allowHit(w, r, "auth:email:"+hashToken(email), l.limits.PerEmail) {
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Lockout counts failed logins per email and refuses further attempts once
+// MaxFailures is reached, until the oldest failure leaves LockoutWindow. Each
+// attempt is recorded as a failure before the handler runs, so concurrent
+// attempts cannot all slip under the threshold. Only a 401 keeps it counted: a
+// successful login clears the count, and any other response (bad input, a
+// database outage) gives the attempt back. Password reset stays available to a
+// locked account.
+func (l *AuthLimiter) Lockout(next http.Handler) http.Handler {
+	return l.lockout(next, http.StatusUnauthorized, func(r *http.Request) string {
+		if email := peekEmail(r); email != "" {
+			return "auth:fail:" + hashToken(email)
+		}
+		return ""
+	})
+}
+
+// PasswordLockout is Lockout for the current_password check of a signed-in
+// user, keyed on the user id, so a stolen access token does not give
+// unlimited guesses at the password. A wrong password is answered with 403;
+// requests without current_password pass untouched. It must run after
+// AuthMiddleware.
+func (l *AuthLimiter) PasswordLockout(next http.Handler) http.Handler {
+	return l.lockout(next, http.StatusForbidden, func(r *http.Request) string {
+		userID, ok := GetUserIDHelper(r.Context())
+		if !ok || peekCurrentPassword(r) == "" {
+			return ""
+		}
+		return "auth:fail:user:" + strconv.FormatInt(userID, 10)
+	})
+}
+
+// lockout reserves a failure under the key of the request before the handler
+// runs and keeps it only when the handler answers with failureStatus. Requests
+// without a key are not limited.
+func (l *AuthLimiter) lockout(next http.Handler, failureStatus int, keyOf func(r *http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := keyOf(r)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		d, err := l.limiter.Allow(r.Context(), key, l.limits.MaxFailures, l.limits.LockoutWindow)
+		if err != nil {
+			log.Println("rate limiter unavailable:", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !d.Allowed {
+			tooManyRequests(w, d.RetryAfter, "account is temporarily locked after repeated failed password attempts")
+			return
+		}
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		switch ww.Status() {
+		case http.StatusOK:
+			err = l.limiter.Reset(r.Context(), key)
+		case failureStatus:
+			// A wrong password stays counted.
+		default:
+			err = l.limiter.Undo(r.Context(), key)
+		}
+		if err != nil {
+			log.Println("rate limiter unavailable:", err)
+		}
+	})
+}
+
+// allowHit records a hit and writes a 429 when it is over the limit.
+func (l *AuthLimiter) allowHit(w http.ResponseWriter, r *http.Request, key string, limit int) bool {
+	d, err := l.limiter.Allow(r.Context(), key, limit, l.limits.Window)
+	if err != nil {
+		log.Println("rate limiter unavailable:", err)
+		return true
+	}
+	if !d.Allowed {
+		tooManyRequests(w, d.RetryAfter, "too many requests, try again later")
+		return false
+	}
+	return true
+}
+
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration, msg string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	json_resp.RespondError(w, http.StatusTooManyRequests, "TOO_MANY_REQUESTS", msg)
+}
+
+// clientIP uses the connection address only: X-Forwarded-For is set by the
+// client unless a trusted proxy rewrites it, so honouring it would let anyone
+// pick a fresh IP per request.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// peekEmail reads the email field of a JSON body and puts the body back for
+// the handler. Emails are compared case-insensitively, like the database does.
+func peekEmail(r *http.Request) string {
+	var req struct {
+		Email string `json:"email"`
+	}
+	peekBody(r, &req)
+	return strings.ToLower(strings.TrimSpace(req.Email))
+}
+
+// peekCurrentPassword reads the current_password field of a JSON body and puts
+// the body back for the handler.
+func peekCurrentPassword(r *http.Request) string {
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+	}
+	peekBody(r, &req)
+	return req.CurrentPassword
+}
+
+// peekBody decodes a JSON body into dst as far as it can and puts the body
+// back for the handler.
+func peekBody(r *http.Request, dst interface{}) {
+	if r.Body == nil {
+		return
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(r.Body, maxPeekBody))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf), r.Body), r.Body}
+	if err != nil {
+		return
+	}
+	json.Unmarshal(buf, dst)
+}
@@ -0,0 +1,200 @@
+package routing
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/egor_lukyanovich/moon_test_application/pkg/ratelimit"
+)
+
+func TestAuthRateLimit(t *testing.T) {
+	limits := AuthLimits{PerIP: 3, PerEmail: 2, Window: time.Minute}
+	limiter := NewAuthLimiter(ratelimit.NewMemory(), limits)
+
+	var seen []string
+	handler := limiter.RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		seen = append(seen, string(body))
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	post := func(ip, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+		req.RemoteAddr = ip + ":1234"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	body := `{"email": "Victim@example.com", "password": "x"}`
+	for i := 0; i < 2; i++ {
+		if rr := post("10.0.0.1", body); rr.Code != http.StatusOK {
+			t.Fatalf("attempt %d: expected 200, got %d", i, rr.Code)
+		}
+	}
+	if seen[0] != body {
+		t.Errorf("expected the handler to get the full body, got %q", seen[0])
+	}
+
+	// Same email from another address and with different case is still
+	// the same account.
+	rr := post("10.0.0.2", `{"email": " victim@EXAMPLE.com "}`)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected per-email limit, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "60" {
+		t.Errorf("expected Retry-After 60, got %q", rr.Header().Get("Retry-After"))
+	}
+	if !strings.Contains(rr.Body.String(), "TOO_MANY_REQUESTS") {
+		t.Errorf("expected error response, got %s", rr.Body.String())
+	}
+
+	// 10.0.0.1 has one request left, whatever the email.
+	if rr := post("10.0.0.1", `{"email": "other@example.com"}`); rr.Code != http.StatusOK {
+		t.Fatalf("expected a fresh email to pass, got %d", rr.Code)
+	}
+	if rr := post("10.0.0.1", `{"email": "third@example.com"}`); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("expected per-IP limit, got %d", rr.Code)
+	}
+	if rr := post("10.0.0.3", `not json`); rr.Code != http.StatusOK {
+		t.Errorf("expected bodies without an email to be limited per IP only, got %d", rr.Code)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	limits := AuthLimits{MaxFailures: 2, LockoutWindow: 15 * time.Minute}
+	limiter := NewAuthLimiter(ratelimit.NewMemory(), limits)
+
+	calls := 0
+	handler := limiter.Lockout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.RawQuery {
+		case "ok":
+			w.WriteHeader(http.StatusOK)
+		case "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+
+	login := func(email, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/login?"+query, strings.NewReader(`{"email": "`+email+`"}`))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	login("a@example.com", "")
+	if rr := login("a@example.com", "ok"); rr.Code != http.StatusOK {
+		t.Fatalf("expected login below the threshold to pass, got %d", rr.Code)
+	}
+
+	// The success cleared the count, so two more failures are needed. Errors
+	// other than a wrong password do not count.
+	login("a@example.com", "")
+	for i := 0; i < 3; i++ {
+		if rr := login("a@example.com", "broken"); rr.Code != http.StatusInternalServerError {
+			t.Fatalf("expected a server error not to lock the account, got %d", rr.Code)
+		}
+	}
+	login("a@example.com", "")
+
+	rr := login("a@example.com", "ok")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected account to be locked, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "900" {
+		t.Errorf("expected Retry-After 900, got %q", rr.Header().Get("Retry-After"))
+	}
+	if calls != 7 {
+		t.Errorf("expected locked attempt not to reach the handler, got %d calls", calls)
+	}
+
+	if rr := login("b@example.com", ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected other accounts to be unaffected, got %d", rr.Code)
+	}
+}
+
+func TestPasswordLockout(t *testing.T) {
+	limits := AuthLimits{MaxFailures: 2, LockoutWindow: 15 * time.Minute}
+	limiter := NewAuthLimiter(ratelimit.NewMemory(), limits)
+
+	calls := 0
+	handler := limiter.PasswordLockout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if strings.Contains(r.URL.RawQuery, "ok") {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+	}))
+
+	patch := func(userID int64, body, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/me?"+query, strings.NewReader(body))
+		req = req.WithContext(WithUserID(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	guess := `{"current_password": "guess", "new_password": "newpassword"}`
+
+	patch(1, guess, "")
+	// A profile update without a password neither counts nor clears failures.
+	if rr := patch(1, `{"display_name": "Mallory"}`, "ok"); rr.Code != http.StatusOK {
+		t.Fatalf("expected profile update to pass, got %d", rr.Code)
+	}
+	patch(1, guess, "")
+
+	if rr := patch(1, guess, "ok"); rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected password changes to be locked, got %d", rr.Code)
+	}
+	if rr := patch(1, `{"display_name": "Mallory"}`, "ok"); rr.Code != http.StatusOK {
+		t.Errorf("expected profile updates to stay available, got %d", rr.Code)
+	}
+	if rr := patch(2, guess, ""); rr.Code != http.StatusForbidden {
+		t.Errorf("expected other users to be unaffected, got %d", rr.Code)
+	}
+	if calls != 5 {
+		t.Errorf("expected locked attempt not to reach the handler, got %d calls", calls)
+	}
+}
+
+func TestLoginLockoutConcurrent(t *testing.T) {
+	limits := AuthLimits{MaxFailures: 3, LockoutWindow: 15 * time.Minute}
+	limiter := NewAuthLimiter(ratelimit.NewMemory(), limits)
+
+	var calls atomic.Int32
+	handler := limiter.Lockout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		// A slow password check keeps every attempt in flight at once.
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+
+	var wg sync.WaitGroup
+	var locked atomic.Int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email": "a@example.com"}`))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code == http.StatusTooManyRequests {
+				locked.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls.Load() != 3 || locked.Load() != 17 {
+		t.Errorf("expected only 3 concurrent attempts to reach the handler, got %d (%d locked)", calls.Load(), locked.Load())
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/egor_lukyanovich/moon_test_application/internal/db"
 	_ "github.com/go-sql-driver/mysql"
@@ -50,7 +52,39 @@ func setupAuthDB(t *testing.T) (*sql.DB, func()) {
 		id BIGINT AUTO_INCREMENT PRIMARY KEY,
 		email VARCHAR(255) NOT NULL UNIQUE,
 		password_hash VARCHAR(255) NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+		display_name VARCHAR(100)
+	);
+	CREATE TABLE refresh_tokens (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		token_hash CHAR(64) NOT NULL UNIQUE,
+		expires_at TIMESTAMP NOT NULL,
+		revoked_at TIMESTAMP NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	CREATE TABLE password_reset_tokens (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		token_hash CHAR(64) NOT NULL UNIQUE,
+		expires_at TIMESTAMP NOT NULL,
+		used_at TIMESTAMP NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	CREATE TABLE team_invitations (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		team_id BIGINT NOT NULL,
+		email VARCHAR(255) NOT NULL,
+		user_id BIGINT NULL,
+		role ENUM('admin', 'member') NOT NULL DEFAULT 'member',
+		status ENUM('pending', 'accepted', 'declined') NOT NULL DEFAULT 'pending',
+		invited_by BIGINT NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		responded_at TIMESTAMP NULL
 	);`
 
 	_, err = database.Exec(schema)
@@ -66,12 +100,40 @@ func setupAuthDB(t *testing.T) (*sql.DB, func()) {
 	return database, cleanup
 }
 
+type memoryDenylist struct {
+	mu      sync.Mutex
+	revoked map[string]bool
+}
+
+func newMemoryDenylist() *memoryDenylist {
+	return &memoryDenylist{revoked: map[string]bool{}}
+}
+
+func (d *memoryDenylist) Revoke(ctx context.Context, jti string, ttl time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.revoked[jti] = true
+	return nil
+}
+
+func (d *memoryDenylist) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.revoked[jti], nil
+}
+
 func TestRegisterAndLogin(t *testing.T) {
 	database, cleanup := setupAuthDB(t)
 	defer cleanup()
 
 	queries := db.New(database)
-	authHandlers := NewAuthHandlers(queries)
+	authHandlers := NewAuthHandlers(queries, database, testKeySet(t), newMemoryDenylist())
+
+	_, err := database.Exec(`INSERT INTO team_invitations (team_id, email, role, invited_by, expires_at)
+		VALUES (1, 'test@avito.ru', 'member', 1, NOW() + INTERVAL 1 DAY)`)
+	if err != nil {
+		t.Fatalf("failed to seed invitation: %v", err)
+	}
 
 	reqBody := []byte(`{"email": "test@avito.ru", "password": "superpassword"}`)
 	reqReg := httptest.NewRequest(http.MethodPost, "/register", bytes.NewBuffer(reqBody))
@@ -91,6 +153,11 @@ func TestRegisterAndLogin(t *testing.T) {
 		t.Errorf("password was not hashed properly")
 	}
 
+	invitation, err := queries.GetInvitationByID(context.Background(), 1)
+	if err != nil || invitation.UserID.Int64 != user.ID {
+		t.Errorf("expected pending invitation to be attached to the new user, got %+v, err: %v", invitation, err)
+	}
+
 	reqLogin := httptest.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(reqBody))
 	rrLogin := httptest.NewRecorder()
 
@@ -116,4 +183,90 @@ func TestRegisterAndLogin(t *testing.T) {
 	if rrBadLogin.Code != http.StatusUnauthorized {
 		t.Errorf("expected status %v for invalid login; got %v", http.StatusUnauthorized, rrBadLogin.Code)
 	}
+
+	weakReqBody := []byte(`{"email": "not-an-email", "password": "short"}`)
+	reqWeak := httptest.NewRequest(http.MethodPost, "/register", bytes.NewBuffer(weakReqBody))
+	rrWeak := httptest.NewRecorder()
+
+	authHandlers.Register(rrWeak, reqWeak)
+
+	if rrWeak.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %v for invalid registration; got %v", http.StatusUnprocessableEntity, rrWeak.Code)
+	}
+}
+
+func TestRefreshAndLogout(t *testing.T) {
+	database, cleanup := setupAuthDB(t)
+	defer cleanup()
+
+	queries := db.New(database)
+	keys := testKeySet(t)
+	denylist := newMemoryDenylist()
+	authHandlers := NewAuthHandlers(queries, database, keys, denylist)
+
+	protected := AuthMiddleware(keys, denylist)(http.HandlerFunc(authHandlers.Logout))
+
+	post := func(handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+	decode := func(rr *httptest.ResponseRecorder) map[string]string {
+		var resp map[string]string
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return resp
+	}
+	refreshBody := func(token string) string {
+		return `{"refresh_token": "` + token + `"}`
+	}
+
+	creds := `{"email": "refresh@avito.ru", "password": "superpassword"}`
+	if rr := post(authHandlers.Register, creds); rr.Code != http.StatusCreated {
+		t.Fatalf("register failed: %d %s", rr.Code, rr.Body.String())
+	}
+
+	login := decode(post(authHandlers.Login, creds))
+	if login["token"] == "" || login["refresh_token"] == "" {
+		t.Fatalf("expected access and refresh tokens, got %v", login)
+	}
+
+	rotated := post(authHandlers.Refresh, refreshBody(login["refresh_token"]))
+	if rotated.Code != http.StatusOK {
+		t.Fatalf("expected refresh to succeed, got %d: %s", rotated.Code, rotated.Body.String())
+	}
+	second := decode(rotated)
+	if second["refresh_token"] == login["refresh_token"] {
+		t.Errorf("expected refresh token to be rotated")
+	}
+
+	// Replaying the rotated-out token must fail and kill the whole token family.
+	if rr := post(authHandlers.Refresh, refreshBody(login["refresh_token"])); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected reused refresh token to be rejected, got %d", rr.Code)
+	}
+	if rr := post(authHandlers.Refresh, refreshBody(second["refresh_token"])); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected token family to be revoked after reuse, got %d", rr.Code)
+	}
+
+	session := decode(post(authHandlers.Login, creds))
+
+	logoutReq := httptest.NewRequest(http.MethodPost, "/logout", strings.NewReader(refreshBody(session["refresh_token"])))
+	logoutReq.Header.Set("Authorization", "Bearer "+session["token"])
+	rrLogout := httptest.NewRecorder()
+	protected.ServeHTTP(rrLogout, logoutReq)
+	if rrLogout.Code != http.StatusOK {
+		t.Fatalf("expected logout to succeed, got %d: %s", rrLogout.Code, rrLogout.Body.String())
+	}
+
+	replayReq := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	replayReq.Header.Set("Authorization", "Bearer "+session["token"])
+	rrReplay := httptest.NewRecorder()
+	protected.ServeHTTP(rrReplay, replayReq)
+	if rrReplay.Code != http.StatusUnauthorized {
+		t.Errorf("expected revoked access token to be rejected, got %d", rrReplay.Code)
+	}
+
+	if rr := post(authHandlers.Refresh, refreshBody(session["refresh_token"])); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected refresh token to be revoked on logout, got %d", rr.Code)
+	}
 }
@@ -0,0 +1,37 @@
+package routing
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type TokenDenylist interface {
+	Revoke(ctx context.Context, jti string, ttl time.Duration) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type RedisDenylist struct {
+	rdb *redis.Client
+}
+
+func NewRedisDenylist(rdb *redis.Client) *RedisDenylist {
+	return &RedisDenylist{rdb: rdb}
+}
+
+func (d *RedisDenylist) key(jti string) string {
+	return "jwt:deny:" + jti
+}
+
+func (d *RedisDenylist) Revoke(ctx context.Context, jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	return d.rdb.Set(ctx, d.key(jti), 1, ttl).Err()
+}
+
+func (d *RedisDenylist) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	n, err := d.rdb.Exists(ctx, d.key(jti)).Result()
+	return n > 0, err
+}
@@ -0,0 +1,282 @@
+package routing
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// legacyKID is assigned to the key from JWT_SECRET. Tokens without a kid
+	// header are verified with it, so tokens issued before rotation support
+	// keep working until they expire.
+	legacyKID = "default"
+	testKID   = "test"
+	testKey   = "test_secret_key_123_not_for_production"
+
+	minSecretLength = 32
+	minRSABits      = 2048
+)
+
+var ErrNoSigningKey = errors.New("no JWT signing key configured: set JWT_PRIVATE_KEYS, JWT_KEYS or JWT_SECRET")
+
+type signingKey struct {
+	method jwt.SigningMethod
+	// sign is nil for verify-only keys.
+	sign   interface{}
+	verify interface{}
+}
+
+// KeySet holds every key that is accepted for verification. Only the active
+// key signs new tokens; the others stay around until the tokens they signed
+// have expired.
+type KeySet struct {
+	activeKID string
+	keys      map[string]signingKey
+	// order keeps keys in configuration order for the JWKS document.
+	order []string
+}
+
+func newKeySet() *KeySet {
+	return &KeySet{keys: map[string]signingKey{}}
+}
+
+func NewKeySet(activeKID string, secrets map[string][]byte) (*KeySet, error) {
+	ks := newKeySet()
+	for kid, secret := range secrets {
+		if err := ks.addHMAC(kid, secret); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ks.setActive(activeKID); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+func (ks *KeySet) add(kid string, key signingKey) error {
+	if kid == "" {
+		return errors.New("JWT key id must not be empty")
+	}
+	if _, ok := ks.keys[kid]; ok {
+		return fmt.Errorf("duplicate JWT key id %q", kid)
+	}
+	ks.keys[kid] = key
+	ks.order = append(ks.order, kid)
+	return nil
+}
+
+func (ks *KeySet) addHMAC(kid string, secret []byte) error {
+	if len(secret) < minSecretLength {
+		return fmt.Errorf("JWT key %q must be at least %d bytes long", kid, minSecretLength)
+	}
+	return ks.add(kid, hmacKey(secret))
+}
+
+func hmacKey(secret []byte) signingKey {
+	return signingKey{method: jwt.SigningMethodHS256, sign: secret, verify: secret}
+}
+
+func (ks *KeySet) addPrivatePEM(kid string, data []byte) error {
+	if key, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		if key.N.BitLen() < minRSABits {
+			return fmt.Errorf("JWT key %q: RSA keys must be at least %d bits", kid, minRSABits)
+		}
+		return ks.add(kid, signingKey{method: jwt.SigningMethodRS256, sign: key, verify: &key.PublicKey})
+	}
+
+	if key, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return fmt.Errorf("JWT key %q: unsupported EdDSA key type", kid)
+		}
+		return ks.add(kid, signingKey{method: jwt.SigningMethodEdDSA, sign: edKey, verify: edKey.Public()})
+	}
+
+	return fmt.Errorf("JWT key %q: expected an RSA or Ed25519 private key in PEM format", kid)
+}
+
+func (ks *KeySet) addPublicPEM(kid string, data []byte) error {
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		if key.N.BitLen() < minRSABits {
+			return fmt.Errorf("JWT key %q: RSA keys must be at least %d bits", kid, minRSABits)
+		}
+		return ks.add(kid, signingKey{method: jwt.SigningMethodRS256, verify: key})
+	}
+
+	if key, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("JWT key %q: unsupported EdDSA key type", kid)
+		}
+		return ks.add(kid, signingKey{method: jwt.SigningMethodEdDSA, verify: edKey})
+	}
+
+	return fmt.Errorf("JWT key %q: expected an RSA or Ed25519 public key in PEM format", kid)
+}
+
+func (ks *KeySet) setActive(kid string) error {
+	key, ok := ks.keys[kid]
+	if !ok {
+		return fmt.Errorf("active JWT key %q is not configured", kid)
+	}
+	if key.sign == nil {
+		return fmt.Errorf("active JWT key %q is verify-only", kid)
+	}
+	ks.activeKID = kid
+	return nil
+}
+
+// parseKeyList splits a "kid:value,kid:value" variable into ordered pairs.
+func parseKeyList(name string) ([][2]string, error) {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return nil, nil
+	}
+
+	var pairs [][2]string
+	for _, entry := range strings.Split(raw, ",") {
+		kid, value, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s entry %q: expected kid:value", name, entry)
+		}
+		pairs = append(pairs, [2]string{kid, value})
+	}
+	return pairs, nil
+}
+
+// LoadKeySet reads signing keys from the environment:
+//
+//	JWT_PRIVATE_KEYS=2024-06:/keys/2024-06.pem     RS256/EdDSA signing keys
+//	JWT_PUBLIC_KEYS=2024-01:/keys/2024-01.pub.pem  verify-only keys
+//	JWT_KEYS=2023-12:secret-a                      HS256 secrets
+//	JWT_ACTIVE_KID=2024-06
+//
+// JWT_ACTIVE_KID defaults to the first private key, then the first HS256
+// secret. A plain JWT_SECRET is still accepted and registered under the
+// "default" kid. Without any key the function fails, unless APP_ENV=test.
+func LoadKeySet() (*KeySet, error) {
+	ks := newKeySet()
+	activeKID := os.Getenv("JWT_ACTIVE_KID")
+	pickDefault := func(kid string) {
+		if activeKID == "" {
+			activeKID = kid
+		}
+	}
+
+	privateKeys, err := parseKeyList("JWT_PRIVATE_KEYS")
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range privateKeys {
+		data, err := os.ReadFile(pair[1])
+		if err != nil {
+			return nil, fmt.Errorf("JWT key %q: %w", pair[0], err)
+		}
+		if err := ks.addPrivatePEM(pair[0], data); err != nil {
+			return nil, err
+		}
+		pickDefault(pair[0])
+	}
+
+	publicKeys, err := parseKeyList("JWT_PUBLIC_KEYS")
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range publicKeys {
+		data, err := os.ReadFile(pair[1])
+		if err != nil {
+			return nil, fmt.Errorf("JWT key %q: %w", pair[0], err)
+		}
+		if err := ks.addPublicPEM(pair[0], data); err != nil {
+			return nil, err
+		}
+	}
+
+	secrets, err := parseKeyList("JWT_KEYS")
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range secrets {
+		if err := ks.addHMAC(pair[0], []byte(pair[1])); err != nil {
+			return nil, err
+		}
+		pickDefault(pair[0])
+	}
+
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		// Deployments from before the length check may run with a shorter
+		// secret, so it still starts; a key in JWT_KEYS takes over signing
+		// while the old secret keeps verifying the tokens it issued.
+		if len(secret) < minSecretLength {
+			log.Printf("WARNING: JWT_SECRET is shorter than %d bytes; add a longer key to JWT_KEYS to sign new tokens", minSecretLength)
+			err = ks.add(legacyKID, hmacKey([]byte(secret)))
+		} else {
+			err = ks.addHMAC(legacyKID, []byte(secret))
+		}
+		if err != nil {
+			return nil, err
+		}
+		pickDefault(legacyKID)
+	}
+
+	if activeKID == "" {
+		if os.Getenv("APP_ENV") != "test" {
+			return nil, ErrNoSigningKey
+		}
+		return NewKeySet(testKID, map[string][]byte{testKID: []byte(testKey)})
+	}
+
+	if err := ks.setActive(activeKID); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	key := ks.keys[ks.activeKID]
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = ks.activeKID
+	return token.SignedString(key.sign)
+}
+
+// keyFunc picks the verification key by the kid header and rejects tokens
+// whose algorithm doesn't match that key.
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = legacyKID
+	}
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.verify, nil
+}
+
+// publicKeys returns the asymmetric verification keys in configuration order.
+// HMAC secrets are never exposed.
+func (ks *KeySet) publicKeys() []jwk {
+	keys := []jwk{}
+	for _, kid := range ks.order {
+		key := ks.keys[kid]
+		switch pub := key.verify.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, rsaJWK(kid, pub))
+		case ed25519.PublicKey:
+			keys = append(keys, ed25519JWK(kid, pub))
+		}
+	}
+	return keys
+}
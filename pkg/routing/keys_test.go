@@ -0,0 +1,249 @@
+package routing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKeySet(t *testing.T) *KeySet {
+	t.Helper()
+	t.Setenv("APP_ENV", "test")
+	t.Setenv("JWT_KEYS", "")
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_PRIVATE_KEYS", "")
+	t.Setenv("JWT_PUBLIC_KEYS", "")
+	keys, err := LoadKeySet()
+	if err != nil {
+		t.Fatalf("failed to load test keys: %v", err)
+	}
+	return keys
+}
+
+var keyEnv = []string{"APP_ENV", "JWT_KEYS", "JWT_SECRET", "JWT_ACTIVE_KID", "JWT_PRIVATE_KEYS", "JWT_PUBLIC_KEYS"}
+
+func TestLoadKeySet(t *testing.T) {
+	secretA := strings.Repeat("a", minSecretLength)
+	secretB := strings.Repeat("b", minSecretLength)
+
+	tests := []struct {
+		name      string
+		env       map[string]string
+		activeKID string
+		wantErr   bool
+	}{
+		{name: "no key outside test mode", env: map[string]string{}, wantErr: true},
+		{name: "no key in test mode", env: map[string]string{"APP_ENV": "test"}, activeKID: testKID},
+		{name: "legacy secret", env: map[string]string{"JWT_SECRET": secretA}, activeKID: legacyKID},
+		{name: "first key is active by default", env: map[string]string{"JWT_KEYS": "new:" + secretA + ",old:" + secretB}, activeKID: "new"},
+		{name: "explicit active key", env: map[string]string{"JWT_KEYS": "new:" + secretA + ",old:" + secretB, "JWT_ACTIVE_KID": "old"}, activeKID: "old"},
+		{name: "unknown active key", env: map[string]string{"JWT_KEYS": "new:" + secretA, "JWT_ACTIVE_KID": "missing"}, wantErr: true},
+		{name: "short legacy secret", env: map[string]string{"JWT_SECRET": "short"}, activeKID: legacyKID},
+		{name: "short legacy secret behind a rotated key", env: map[string]string{"JWT_KEYS": "new:" + secretA, "JWT_SECRET": "short"}, activeKID: "new"},
+		{name: "short rotated key", env: map[string]string{"JWT_KEYS": "new:short"}, wantErr: true},
+		{name: "malformed entry", env: map[string]string{"JWT_KEYS": secretA}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range keyEnv {
+				t.Setenv(name, tt.env[name])
+			}
+
+			keys, err := LoadKeySet()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got key set with active kid %q", keys.activeKID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if keys.activeKID != tt.activeKID {
+				t.Errorf("expected active kid %q, got %q", tt.activeKID, keys.activeKID)
+			}
+		})
+	}
+
+	t.Run("missing key error", func(t *testing.T) {
+		for _, name := range keyEnv {
+			t.Setenv(name, "")
+		}
+		if _, err := LoadKeySet(); !errors.Is(err, ErrNoSigningKey) {
+			t.Errorf("expected ErrNoSigningKey, got %v", err)
+		}
+	})
+}
+
+func TestKeyRotation(t *testing.T) {
+	secretOld := []byte(strings.Repeat("o", minSecretLength))
+	secretNew := []byte(strings.Repeat("n", minSecretLength))
+
+	before, err := NewKeySet("old", map[string][]byte{"old": secretOld})
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := NewKeySet("new", map[string][]byte{"old": secretOld, "new": secretNew})
+	if err != nil {
+		t.Fatal(err)
+	}
+	retired, err := NewKeySet("new", map[string][]byte{"new": secretNew})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldToken, err := signAccessToken(before, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := signAccessToken(after, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(keys *KeySet, token string, want int) {
+		t.Helper()
+		checkToken(t, keys, token, want)
+	}
+
+	check(after, oldToken, http.StatusOK)
+	check(after, newToken, http.StatusOK)
+	check(before, newToken, http.StatusUnauthorized)
+	check(retired, oldToken, http.StatusUnauthorized)
+}
+
+func checkToken(t *testing.T, keys *KeySet, token string, want int) {
+	t.Helper()
+	handler := AuthMiddleware(keys, newMemoryDenylist())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != want {
+		t.Errorf("expected status %d, got %d: %s", want, rr.Code, rr.Body.String())
+	}
+}
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAsymmetricKeysAndJWKS(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPath := writePEM(t, dir, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	rsaPubDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPubPath := writePEM(t, dir, "rsa.pub.pem", "PUBLIC KEY", rsaPubDER)
+
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPath := writePEM(t, dir, "ed.pem", "PRIVATE KEY", edDER)
+	edPubDER, err := x509.MarshalPKIXPublicKey(edPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPubPath := writePEM(t, dir, "ed.pub.pem", "PUBLIC KEY", edPubDER)
+
+	load := func(env map[string]string) *KeySet {
+		t.Helper()
+		for _, name := range keyEnv {
+			t.Setenv(name, env[name])
+		}
+		keys, err := LoadKeySet()
+		if err != nil {
+			t.Fatalf("failed to load keys: %v", err)
+		}
+		return keys
+	}
+
+	issuer := load(map[string]string{
+		"JWT_PRIVATE_KEYS": "ed:" + edPath + ",rsa:" + rsaPath,
+		"JWT_KEYS":         "hmac:" + strings.Repeat("h", minSecretLength),
+	})
+	if issuer.activeKID != "ed" {
+		t.Fatalf("expected first private key to be active, got %q", issuer.activeKID)
+	}
+
+	edToken, err := signAccessToken(issuer, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.activeKID = "rsa"
+	rsaToken, err := signAccessToken(issuer, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkToken(t, issuer, edToken, http.StatusOK)
+	checkToken(t, issuer, rsaToken, http.StatusOK)
+
+	// Another service only needs the public halves.
+	verifier := load(map[string]string{
+		"JWT_PUBLIC_KEYS": "ed:" + edPubPath + ",rsa:" + rsaPubPath,
+		"JWT_KEYS":        "local:" + strings.Repeat("l", minSecretLength),
+	})
+	checkToken(t, verifier, edToken, http.StatusOK)
+	checkToken(t, verifier, rsaToken, http.StatusOK)
+
+	for _, name := range keyEnv {
+		t.Setenv(name, "")
+	}
+	t.Setenv("JWT_PUBLIC_KEYS", "ed:"+edPubPath)
+	t.Setenv("JWT_ACTIVE_KID", "ed")
+	if _, err := LoadKeySet(); err == nil {
+		t.Errorf("expected a verify-only active key to be rejected")
+	}
+
+	rr := httptest.NewRecorder()
+	JWKSHandler(issuer)(rr, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Keys) != 2 {
+		t.Fatalf("expected 2 public keys (no HMAC secrets), got %+v", doc.Keys)
+	}
+	if doc.Keys[0].Kid != "ed" || doc.Keys[0].Kty != "OKP" || doc.Keys[0].Crv != "Ed25519" || doc.Keys[0].X == "" {
+		t.Errorf("unexpected Ed25519 JWK: %+v", doc.Keys[0])
+	}
+	if doc.Keys[1].Kid != "rsa" || doc.Keys[1].Kty != "RSA" || doc.Keys[1].E != "AQAB" || doc.Keys[1].N == "" {
+		t.Errorf("unexpected RSA JWK: %+v", doc.Keys[1])
+	}
+}
@@ -0,0 +1,221 @@
+package routing
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+	json_resp "github.com/egor_lukyanovich/moon_test_application/pkg/json"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/mail"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/validation"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	passwordResetTTL = time.Hour
+	// passwordResetWorkTimeout bounds the background work of a reset request,
+	// SMTP delivery included.
+	passwordResetWorkTimeout = time.Minute
+)
+
+type PasswordResetHandlers struct {
+	q      *db.Queries
+	db     *sql.DB
+	mailer mail.Mailer
+	// resetURL is the frontend page that receives the token as ?token=...;
+	// when empty the bare token is sent.
+	resetURL string
+	// pending tracks reset requests still being processed after the response.
+	pending sync.WaitGroup
+}
+
+func NewPasswordResetHandlers(q *db.Queries, database *sql.DB, mailer mail.Mailer, resetURL string) *PasswordResetHandlers {
+	return &PasswordResetHandlers{q: q, db: database, mailer: mailer, resetURL: resetURL}
+}
+
+// RequestReset always answers 202 so the endpoint cannot be used to find out
+// which emails are registered. The response is sent before the user is even
+// looked up: the token is issued and mailed in the background, so known and
+// unknown emails take the same time to answer. Older unused tokens of the user
+// are invalidated.
+func (h *PasswordResetHandlers) RequestReset(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json_resp.RespondError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json body")
+		return
+	}
+
+	v := validation.New()
+	v.Email("email", req.Email)
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
+	// The work outlives the request, so it must not inherit its context.
+	ctx, cancel := context.WithTimeout(context.Background(), passwordResetWorkTimeout)
+	h.pending.Add(1)
+	go func() {
+		defer h.pending.Done()
+		defer cancel()
+		if err := h.issueToken(ctx, req.Email); err != nil {
+			log.Println("password reset failed:", err)
+		}
+	}()
+
+	json_resp.RespondJSON(w, http.StatusAccepted, map[string]string{"status": "if the account exists, a reset link has been sent"})
+}
+
+// Wait blocks until reset requests that were already answered are processed.
+// Call it after the server has stopped accepting requests and before closing
+// the database; each request gives up after passwordResetWorkTimeout.
+func (h *PasswordResetHandlers) Wait() {
+	h.pending.Wait()
+}
+
+// issueToken stores a new reset token for the user with the given email and
+// mails it. An unknown email is not an error.
+func (h *PasswordResetHandlers) issueToken(ctx context.Context, email string) error {
+	user, err := h.q.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("fetch user: %w", err)
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return fmt.Errorf("generate token: %w", err)
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("start tx: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := h.q.WithTx(tx)
+
+	if err := qtx.InvalidateUserPasswordResetTokens(ctx, user.ID); err != nil {
+		return fmt.Errorf("invalidate reset tokens: %w", err)
+	}
+
+	err = qtx.CreatePasswordResetToken(ctx, db.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("store reset token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+
+	if err := h.mailer.Send(ctx, h.resetMessage(user.Email, token)); err != nil {
+		return fmt.Errorf("send mail: %w", err)
+	}
+	return nil
+}
+
+func (h *PasswordResetHandlers) resetMessage(email, token string) mail.Message {
+	link := token
+	if h.resetURL != "" {
+		link = h.resetURL + "?token=" + url.QueryEscape(token)
+	}
+
+	return mail.Message{
+		To:      email,
+		Subject: "Password reset",
+		Body: "Someone requested a password reset for your account.\n\n" +
+			"Use this to set a new password within " + passwordResetTTL.String() + ":\n" + link + "\n\n" +
+			"If it was not you, ignore this email.\n",
+	}
+}
+
+// ConfirmReset sets a new password. The token is consumed atomically, so it
+// works only once, and every session of the user is revoked.
+func (h *PasswordResetHandlers) ConfirmReset(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json_resp.RespondError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json body")
+		return
+	}
+
+	v := validation.New()
+	v.Required("token", req.Token)
+	v.Password("new_password", req.NewPassword)
+	if !v.Valid() {
+		json_resp.RespondValidationError(w, v.Errors())
+		return
+	}
+
+	stored, err := h.q.GetPasswordResetTokenByHash(r.Context(), hashToken(req.Token))
+	if err != nil {
+		json_resp.RespondError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid or expired reset token")
+		return
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to hash password")
+		return
+	}
+
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to start tx")
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.q.WithTx(tx)
+
+	used, err := qtx.UsePasswordResetToken(r.Context(), stored.ID)
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to use reset token")
+		return
+	}
+	if used == 0 {
+		json_resp.RespondError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid or expired reset token")
+		return
+	}
+
+	err = qtx.UpdateUserPassword(r.Context(), db.UpdateUserPasswordParams{
+		PasswordHash: string(passwordHash),
+		ID:           stored.UserID,
+	})
+	if err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to update password")
+		return
+	}
+
+	if err := qtx.InvalidateUserPasswordResetTokens(r.Context(), stored.UserID); err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to invalidate reset tokens")
+		return
+	}
+	if err := qtx.RevokeUserRefreshTokens(r.Context(), stored.UserID); err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to revoke refresh tokens")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		json_resp.RespondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to commit tx")
+		return
+	}
+
+	json_resp.RespondJSON(w, http.StatusOK, map[string]string{"status": "password has been reset"})
+}
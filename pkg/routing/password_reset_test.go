@@ -0,0 +1,127 @@
+package routing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+	"github.com/egor_lukyanovich/moon_test_application/pkg/mail"
+)
+
+// blockingMailer holds every Send until release is closed.
+type blockingMailer struct {
+	release chan struct{}
+}
+
+func (m *blockingMailer) Send(ctx context.Context, msg mail.Message) error {
+	<-m.release
+	return nil
+}
+
+func TestPasswordReset(t *testing.T) {
+	database, cleanup := setupAuthDB(t)
+	defer cleanup()
+
+	queries := db.New(database)
+	authHandlers := NewAuthHandlers(queries, database, testKeySet(t), newMemoryDenylist())
+
+	var outbox bytes.Buffer
+	resetHandlers := NewPasswordResetHandlers(queries, database, mail.NewLogMailer(&outbox, "app@example.com"), "https://app.example.com/reset")
+
+	post := func(handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+	// Reset mails are sent after the response, so wait for them before reading
+	// the outbox.
+	requestReset := func(body string) *httptest.ResponseRecorder {
+		rr := post(resetHandlers.RequestReset, body)
+		resetHandlers.Wait()
+		return rr
+	}
+	tokenPattern := regexp.MustCompile(`reset\?token=([A-Za-z0-9_-]+)`)
+	lastToken := func() string {
+		matches := tokenPattern.FindAllStringSubmatch(outbox.String(), -1)
+		if len(matches) == 0 {
+			t.Fatalf("expected a reset link in the outbox, got:\n%s", outbox.String())
+		}
+		return matches[len(matches)-1][1]
+	}
+
+	if rr := post(authHandlers.Register, `{"email": "reset@avito.ru", "password": "oldpassword"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("register failed: %d %s", rr.Code, rr.Body.String())
+	}
+	session := post(authHandlers.Login, `{"email": "reset@avito.ru", "password": "oldpassword"}`)
+	if session.Code != http.StatusOK {
+		t.Fatalf("login failed: %d", session.Code)
+	}
+
+	if rr := requestReset(`{"email": "nobody@avito.ru"}`); rr.Code != http.StatusAccepted {
+		t.Errorf("expected unknown email to get 202, got %d", rr.Code)
+	}
+	if outbox.Len() != 0 {
+		t.Errorf("expected no mail for an unknown email")
+	}
+
+	// A slow mail server must not delay the answer for a known email, or the
+	// response time would tell registered emails apart.
+	slow := &blockingMailer{release: make(chan struct{})}
+	slowHandlers := NewPasswordResetHandlers(queries, database, slow, "")
+	if rr := post(slowHandlers.RequestReset, `{"email": "reset@avito.ru"}`); rr.Code != http.StatusAccepted {
+		t.Errorf("expected 202 before the mail is delivered, got %d", rr.Code)
+	}
+	close(slow.release)
+	slowHandlers.Wait()
+
+	requestReset(`{"email": "reset@avito.ru"}`)
+	firstToken := lastToken()
+	if rr := requestReset(`{"email": "reset@avito.ru"}`); rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rr.Code)
+	}
+	token := lastToken()
+
+	stored, err := queries.GetPasswordResetTokenByHash(context.Background(), hashToken(token))
+	if err != nil || stored.TokenHash == token {
+		t.Errorf("expected only the token hash to be stored, got %+v (%v)", stored, err)
+	}
+
+	if rr := post(resetHandlers.ConfirmReset, `{"token": "`+firstToken+`", "new_password": "newpassword"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected a superseded token to be rejected, got %d", rr.Code)
+	}
+	if rr := post(resetHandlers.ConfirmReset, `{"token": "`+token+`", "new_password": "short"}`); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected weak password to get 422, got %d", rr.Code)
+	}
+
+	if rr := post(resetHandlers.ConfirmReset, `{"token": "`+token+`", "new_password": "newpassword"}`); rr.Code != http.StatusOK {
+		t.Fatalf("expected reset to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := post(resetHandlers.ConfirmReset, `{"token": "`+token+`", "new_password": "otherpassword"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected a used token to be rejected, got %d", rr.Code)
+	}
+
+	if rr := post(authHandlers.Login, `{"email": "reset@avito.ru", "password": "oldpassword"}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected the old password to stop working, got %d", rr.Code)
+	}
+	if rr := post(authHandlers.Login, `{"email": "reset@avito.ru", "password": "newpassword"}`); rr.Code != http.StatusOK {
+		t.Errorf("expected the new password to work, got %d", rr.Code)
+	}
+
+	var tokens map[string]string
+	json.NewDecoder(session.Body).Decode(&tokens)
+	if rr := post(authHandlers.Refresh, `{"refresh_token": "`+tokens["refresh_token"]+`"}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected sessions from before the reset to be revoked, got %d", rr.Code)
+	}
+
+	_, _ = database.Exec("UPDATE password_reset_tokens SET used_at = NULL, expires_at = NOW() - INTERVAL 1 MINUTE")
+	if rr := post(resetHandlers.ConfirmReset, `{"token": "`+token+`", "new_password": "newpassword"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected an expired token to be rejected, got %d", rr.Code)
+	}
+}
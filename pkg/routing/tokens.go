@@ -0,0 +1,78 @@
+package routing
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/egor_lukyanovich/moon_test_application/internal/db"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+type accessToken struct {
+	ID        string
+	ExpiresAt time.Time
+}
+
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func signAccessToken(keys *KeySet, userID int64) (string, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	return keys.sign(jwt.MapClaims{
+		"sub": userID,
+		"jti": jti,
+		"exp": now.Add(accessTokenTTL).Unix(),
+		"iat": now.Unix(),
+	})
+}
+
+func issueTokens(ctx context.Context, q *db.Queries, keys *KeySet, userID int64) (map[string]string, error) {
+	access, err := signAccessToken(keys, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	err = q.CreateRefreshToken(ctx, db.CreateRefreshTokenParams{
+		UserID:    userID,
+		TokenHash: hashToken(refresh),
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"token":         access,
+		"refresh_token": refresh,
+		"token_type":    "Bearer",
+	}, nil
+}
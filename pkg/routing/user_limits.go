@@ -0,0 +1,86 @@
+package routing
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/egor_lukyanovich/moon_test_application/pkg/ratelimit"
+)
+
+// APILimits configures per-user quotas of the protected routes. Routes are
+// grouped into scopes; a scope without an entry in Scopes uses Default.
+type APILimits struct {
+	Default ratelimit.Rate
+	Scopes  map[string]ratelimit.Rate
+}
+
+func DefaultAPILimits() APILimits {
+	return APILimits{
+		Default: ratelimit.Rate{Burst: 120, Period: time.Minute},
+		Scopes: map[string]ratelimit.Rate{
+			// Filtered listing and full-text search hit the database hard.
+			"tasks:list": {Burst: 30, Period: time.Minute},
+			// Stats run aggregate queries over whole teams.
+			"stats": {Burst: 10, Period: time.Minute},
+		},
+	}
+}
+
+func (l APILimits) rate(scope string) ratelimit.Rate {
+	if rate, ok := l.Scopes[scope]; ok {
+		return rate
+	}
+	return l.Default
+}
+
+// UserLimiter enforces per-user token buckets on routes behind
+// AuthMiddleware. Like AuthLimiter it lets requests through when the bucket
+// store fails.
+type UserLimiter struct {
+	buckets ratelimit.Buckets
+	limits  APILimits
+}
+
+func NewUserLimiter(buckets ratelimit.Buckets, limits APILimits) *UserLimiter {
+	return &UserLimiter{buckets: buckets, limits: limits}
+}
+
+// Limit returns a middleware that takes a token from the caller's bucket of
+// scope and reports the quota in X-RateLimit-* headers. Every scope has its own
+// buckets; when scopes are nested, the headers describe the innermost one.
+func (l *UserLimiter) Limit(scope string) func(http.Handler) http.Handler {
+	rate := l.limits.rate(scope)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := GetUserIDHelper(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := "api:" + scope + ":" + strconv.FormatInt(userID, 10)
+			d, err := l.buckets.Take(r.Context(), key, rate)
+			if err != nil {
+				log.Println("rate limiter unavailable:", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.Itoa(rate.Burst))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+			h.Set("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(d.ResetAfter.Seconds()))))
+
+			if !d.Allowed {
+				tooManyRequests(w, d.RetryAfter, "rate limit exceeded, try again later")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
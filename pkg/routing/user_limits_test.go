@@ -0,0 +1,59 @@
+package routing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/egor_lukyanovich/moon_test_application/pkg/ratelimit"
+)
+
+func TestUserLimiter(t *testing.T) {
+	limiter := NewUserLimiter(ratelimit.NewMemoryBuckets(), APILimits{
+		Default: ratelimit.Rate{Burst: 5, Period: time.Minute},
+		Scopes:  map[string]ratelimit.Rate{"stats": {Burst: 2, Period: time.Minute}},
+	})
+	handler := limiter.Limit("stats")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	get := func(userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/stats/teams", nil)
+		if userID != 0 {
+			req = req.WithContext(WithUserID(req.Context(), userID))
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get(1)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if rr.Header().Get("X-RateLimit-Limit") != "2" || rr.Header().Get("X-RateLimit-Remaining") != "1" ||
+		rr.Header().Get("X-RateLimit-Reset") != "30" {
+		t.Errorf("unexpected quota headers: %v", rr.Header())
+	}
+
+	get(1)
+	rr = get(1)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once the bucket is empty, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "30" || rr.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("unexpected headers on rejection: %v", rr.Header())
+	}
+
+	if rr := get(2); rr.Code != http.StatusOK {
+		t.Errorf("expected other users to have their own bucket, got %d", rr.Code)
+	}
+	if rr := get(0); rr.Code != http.StatusOK || rr.Header().Get("X-RateLimit-Limit") != "" {
+		t.Errorf("expected anonymous requests to pass unmetered, got %d %v", rr.Code, rr.Header())
+	}
+
+	if rate := limiter.limits.rate("unknown"); rate.Burst != 5 {
+		t.Errorf("expected unknown scopes to use the default rate, got %+v", rate)
+	}
+}
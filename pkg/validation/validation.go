@@ -0,0 +1,88 @@
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	models "github.com/egor_lukyanovich/moon_test_application/internal/models"
+)
+
+const (
+	MinPasswordLength = 8
+	// bcrypt ignores everything after the 72nd byte.
+	MaxPasswordBytes = 72
+	MaxEmailLength   = 255
+)
+
+// Validator collects at most one error per field, so checks can be chained
+// without piling up messages for an already invalid value.
+type Validator struct {
+	errs []models.FieldError
+}
+
+func New() *Validator {
+	return &Validator{}
+}
+
+func (v *Validator) Valid() bool {
+	return len(v.errs) == 0
+}
+
+func (v *Validator) Errors() []models.FieldError {
+	return v.errs
+}
+
+func (v *Validator) hasError(field string) bool {
+	for _, e := range v.errs {
+		if e.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Validator) Check(ok bool, field, message string) {
+	if ok || v.hasError(field) {
+		return
+	}
+	v.errs = append(v.errs, models.FieldError{Field: field, Message: message})
+}
+
+func (v *Validator) Required(field, value string) {
+	v.Check(strings.TrimSpace(value) != "", field, "is required")
+}
+
+func (v *Validator) MaxLength(field, value string, max int) {
+	v.Check(utf8.RuneCountInString(value) <= max, field, fmt.Sprintf("must be at most %d characters long", max))
+}
+
+func (v *Validator) Positive(field string, value int64) {
+	v.Check(value > 0, field, "must be a positive integer")
+}
+
+func (v *Validator) OneOf(field, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.Check(false, field, "must be one of: "+strings.Join(allowed, ", "))
+}
+
+func (v *Validator) Email(field, value string) {
+	v.Required(field, value)
+	v.MaxLength(field, value, MaxEmailLength)
+
+	addr, err := mail.ParseAddress(value)
+	v.Check(err == nil && addr.Address == value, field, "must be a valid email address")
+}
+
+func (v *Validator) Password(field, value string) {
+	v.Check(utf8.RuneCountInString(value) >= MinPasswordLength, field,
+		fmt.Sprintf("must be at least %d characters long", MinPasswordLength))
+	v.Check(len(value) <= MaxPasswordBytes, field,
+		fmt.Sprintf("must be at most %d bytes long", MaxPasswordBytes))
+	v.Check(strings.TrimSpace(value) != "", field, "must not consist of whitespace only")
+}
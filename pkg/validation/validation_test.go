@@ -0,0 +1,79 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidator(t *testing.T) {
+	tests := []struct {
+		name   string
+		check  func(v *Validator)
+		fields []string
+	}{
+		{
+			name: "valid input",
+			check: func(v *Validator) {
+				v.Required("title", "Task")
+				v.MaxLength("title", "Task", 255)
+				v.OneOf("status", "todo", "todo", "in_progress", "done")
+				v.Positive("team_id", 1)
+				v.Email("email", "user@example.com")
+				v.Password("password", "superpassword")
+			},
+		},
+		{
+			name: "one error per field",
+			check: func(v *Validator) {
+				v.Required("title", "   ")
+				v.MaxLength("title", strings.Repeat("a", 300), 255)
+			},
+			fields: []string{"title"},
+		},
+		{
+			name: "enum and ids",
+			check: func(v *Validator) {
+				v.OneOf("status", "archived", "todo", "in_progress", "done")
+				v.Positive("team_id", 0)
+			},
+			fields: []string{"status", "team_id"},
+		},
+		{
+			name: "bad emails",
+			check: func(v *Validator) {
+				v.Email("empty", "")
+				v.Email("no_at", "user.example.com")
+				v.Email("display_name", "User <user@example.com>")
+			},
+			fields: []string{"empty", "no_at", "display_name"},
+		},
+		{
+			name: "password policy",
+			check: func(v *Validator) {
+				v.Password("short", "abc")
+				v.Password("long", strings.Repeat("x", MaxPasswordBytes+1))
+				v.Password("blank", strings.Repeat(" ", MinPasswordLength))
+			},
+			fields: []string{"short", "long", "blank"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New()
+			tt.check(v)
+
+			if v.Valid() != (len(tt.fields) == 0) {
+				t.Errorf("expected Valid() to be %v, errors: %v", len(tt.fields) == 0, v.Errors())
+			}
+
+			var got []string
+			for _, e := range v.Errors() {
+				got = append(got, e.Field)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.fields, ",") {
+				t.Errorf("expected errors for %v, got %v", tt.fields, v.Errors())
+			}
+		})
+	}
+}
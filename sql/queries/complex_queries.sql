@@ -1,16 +1,20 @@
 -- name: GetTeamStats :many
 SELECT 
+    t.id AS team_id,
     t.name AS team_name,
     COUNT(DISTINCT tm.user_id) AS members_count,
-    COUNT(DISTINCT task.id) AS done_tasks_last_7_days
+    COUNT(DISTINCT task.id) AS done_tasks
 FROM teams t
 LEFT JOIN team_members tm ON t.id = tm.team_id
 LEFT JOIN tasks task ON t.id = task.team_id 
     AND task.status = 'done' 
-    AND task.updated_at >= NOW() - INTERVAL 7 DAY
+    AND task.updated_at >= sqlc.arg('from_date')
+    AND task.updated_at < sqlc.arg('to_date')
+WHERE (sqlc.narg('member_id') IS NULL OR t.id IN (SELECT team_id FROM team_members WHERE user_id = sqlc.narg('member_id')))
+    AND (sqlc.narg('team_id') IS NULL OR t.id = sqlc.narg('team_id'))
 GROUP BY t.id, t.name;
 
--- name: GetTopUsersPerTeam :many
+-- name: GetTopCreatorsPerTeam :many
 SELECT 
     final_tab.team_id,
     final_tab.user_id,
@@ -28,15 +32,79 @@ FROM (
             created_by AS user_id,
             COUNT(*) AS task_count
         FROM tasks
-        WHERE created_at >= NOW() - INTERVAL 1 MONTH
+        WHERE created_at >= sqlc.arg('from_date')
+            AND created_at < sqlc.arg('to_date')
+            AND (sqlc.narg('member_id') IS NULL OR team_id IN (SELECT team_id FROM team_members WHERE user_id = sqlc.narg('member_id')))
+            AND (sqlc.narg('team_id') IS NULL OR team_id = sqlc.narg('team_id'))
         GROUP BY team_id, created_by
     ) AS user_counts
 ) AS final_tab
-WHERE final_tab.rank_num <= 3;
+WHERE final_tab.rank_num <= sqlc.arg('top_n');
+
+-- name: GetTopCompletersPerTeam :many
+SELECT 
+    final_tab.team_id,
+    final_tab.user_id,
+    final_tab.task_count,
+    final_tab.rank_num
+FROM (
+    SELECT 
+        user_counts.team_id,
+        user_counts.user_id,
+        user_counts.task_count,
+        DENSE_RANK() OVER (PARTITION BY user_counts.team_id ORDER BY user_counts.task_count DESC) as rank_num
+    FROM (
+        SELECT 
+            task.team_id,
+            th.changed_by AS user_id,
+            COUNT(DISTINCT th.task_id) AS task_count
+        FROM task_history th
+        JOIN tasks task ON th.task_id = task.id
+        WHERE th.change_type = 'status_update'
+            AND th.new_value = 'done'
+            AND th.changed_by IS NOT NULL
+            AND th.created_at >= sqlc.arg('from_date')
+            AND th.created_at < sqlc.arg('to_date')
+            AND (sqlc.narg('member_id') IS NULL OR task.team_id IN (SELECT team_id FROM team_members WHERE user_id = sqlc.narg('member_id')))
+            AND (sqlc.narg('team_id') IS NULL OR task.team_id = sqlc.narg('team_id'))
+        GROUP BY task.team_id, th.changed_by
+    ) AS user_counts
+) AS final_tab
+WHERE final_tab.rank_num <= sqlc.arg('top_n');
+
+-- name: GetTopAssigneesPerTeam :many
+SELECT 
+    final_tab.team_id,
+    final_tab.user_id,
+    final_tab.task_count,
+    final_tab.rank_num
+FROM (
+    SELECT 
+        user_counts.team_id,
+        user_counts.user_id,
+        user_counts.task_count,
+        DENSE_RANK() OVER (PARTITION BY user_counts.team_id ORDER BY user_counts.task_count DESC) as rank_num
+    FROM (
+        SELECT 
+            team_id,
+            assignee_id AS user_id,
+            COUNT(*) AS task_count
+        FROM tasks
+        WHERE assignee_id IS NOT NULL
+            AND created_at >= sqlc.arg('from_date')
+            AND created_at < sqlc.arg('to_date')
+            AND (sqlc.narg('member_id') IS NULL OR team_id IN (SELECT team_id FROM team_members WHERE user_id = sqlc.narg('member_id')))
+            AND (sqlc.narg('team_id') IS NULL OR team_id = sqlc.narg('team_id'))
+        GROUP BY team_id, assignee_id
+    ) AS user_counts
+) AS final_tab
+WHERE final_tab.rank_num <= sqlc.arg('top_n');
 
 -- name: FindInvalidTasks :many
 SELECT t.id, t.title, t.team_id, t.assignee_id
 FROM tasks t
 LEFT JOIN team_members tm ON t.team_id = tm.team_id AND t.assignee_id = tm.user_id
 WHERE t.assignee_id IS NOT NULL 
-  AND tm.user_id IS NULL;
\ No newline at end of file
+  AND tm.user_id IS NULL
+  AND (sqlc.narg('member_id') IS NULL OR t.team_id IN (SELECT team_id FROM team_members WHERE user_id = sqlc.narg('member_id')))
+  AND (sqlc.narg('team_id') IS NULL OR t.team_id = sqlc.narg('team_id'));
\ No newline at end of file
@@ -0,0 +1,32 @@
+-- name: CreateInvitation :execresult
+INSERT INTO team_invitations (team_id, email, user_id, role, invited_by, expires_at)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: GetInvitationByID :one
+SELECT * FROM team_invitations
+WHERE id = ? LIMIT 1;
+
+-- name: GetPendingInvitation :one
+SELECT * FROM team_invitations
+WHERE team_id = ? AND email = ? AND status = 'pending' AND expires_at > NOW()
+LIMIT 1;
+
+-- name: ListUserPendingInvitations :many
+SELECT i.id, i.team_id, t.name AS team_name, i.role, i.invited_by, i.expires_at, i.created_at
+FROM team_invitations i
+JOIN teams t ON t.id = i.team_id
+WHERE i.user_id = ? AND i.status = 'pending' AND i.expires_at > NOW()
+ORDER BY i.created_at DESC, i.id DESC;
+
+-- name: RespondToInvitation :execrows
+UPDATE team_invitations
+SET status = ?, responded_at = NOW()
+WHERE id = ? AND status = 'pending' AND expires_at > NOW();
+
+-- name: AttachInvitationsToUser :exec
+UPDATE team_invitations
+SET user_id = ?
+WHERE email = ? AND user_id IS NULL;
+
+-- name: DeleteTeamInvitations :execrows
+DELETE FROM team_invitations WHERE team_id = ?;
\ No newline at end of file
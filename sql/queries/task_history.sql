@@ -18,4 +18,20 @@ SELECT tc.*, u.email as user_email
 FROM task_comments tc
 JOIN users u ON tc.user_id = u.id
 WHERE tc.task_id = ?
-ORDER BY tc.created_at ASC;
\ No newline at end of file
+ORDER BY tc.created_at ASC;
+
+-- name: GetTaskCommentByID :one
+SELECT * FROM task_comments
+WHERE id = ? LIMIT 1;
+
+-- name: UpdateTaskComment :exec
+UPDATE task_comments
+SET content = ?
+WHERE id = ?;
+
+-- name: DeleteTaskComment :exec
+DELETE FROM task_comments WHERE id = ?;
+
+-- name: DeleteTeamTaskComments :execrows
+DELETE FROM task_comments
+WHERE task_id IN (SELECT id FROM tasks WHERE team_id = ?);
\ No newline at end of file
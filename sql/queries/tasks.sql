@@ -1,6 +1,6 @@
 -- name: CreateTask :execresult
-INSERT INTO tasks (title, description, status, team_id, assignee_id, created_by) 
-VALUES (?, ?, ?, ?, ?, ?);
+INSERT INTO tasks (title, description, status, team_id, assignee_id, created_by, position) 
+VALUES (?, ?, ?, ?, ?, ?, ?);
 
 -- name: GetTaskByID :one
 SELECT * FROM tasks 
@@ -11,14 +11,58 @@ UPDATE tasks
 SET title = ?, description = ?, status = ?, assignee_id = ? 
 WHERE id = ?;
 
+-- name: PatchTask :exec
+UPDATE tasks
+SET
+    title = COALESCE(sqlc.narg('title'), title),
+    description = IF(sqlc.arg('set_description'), sqlc.narg('description'), description),
+    status = COALESCE(sqlc.narg('status'), status),
+    assignee_id = IF(sqlc.arg('set_assignee'), sqlc.narg('assignee_id'), assignee_id)
+WHERE id = sqlc.arg('id');
+
 -- name: DeleteTask :exec
 DELETE FROM tasks WHERE id = ?;
 
--- name: ListTasks :many
+-- name: ListTeamTasksByAssignee :many
+SELECT * FROM tasks
+WHERE team_id = ? AND assignee_id = ?
+ORDER BY id;
+
+-- name: DeleteTeamTasks :execrows
+DELETE FROM tasks WHERE team_id = ?;
+
+-- name: GetTaskByIDForUpdate :one
+SELECT * FROM tasks
+WHERE id = ? LIMIT 1
+FOR UPDATE;
+
+-- name: LastTaskPosition :one
+SELECT CAST(COALESCE(MAX(position), 0) AS SIGNED) AS last_position
+FROM tasks
+WHERE team_id = ? AND status = ?;
+
+-- name: ListColumnPositions :many
+SELECT id, position FROM tasks
+WHERE team_id = ? AND status = ?
+ORDER BY position, id;
+
+-- name: ListBoardColumn :many
 SELECT * FROM tasks
-WHERE 
-    team_id = ?
-    AND (sqlc.narg('status') IS NULL OR status = sqlc.narg('status'))
-    AND (sqlc.narg('assignee_id') IS NULL OR assignee_id = sqlc.narg('assignee_id'))
-ORDER BY created_at DESC
-LIMIT ? OFFSET ?;
\ No newline at end of file
+WHERE team_id = ? AND status = ?
+ORDER BY position, id
+LIMIT ?;
+
+-- name: CountTeamTasksByStatus :many
+SELECT status, COUNT(*) AS total FROM tasks
+WHERE team_id = ?
+GROUP BY status;
+
+-- name: MoveTask :exec
+UPDATE tasks
+SET status = ?, position = ?
+WHERE id = ?;
+
+-- name: SetTaskPosition :exec
+UPDATE tasks
+SET position = ?, updated_at = updated_at
+WHERE id = ?;
\ No newline at end of file
@@ -18,4 +18,41 @@ WHERE team_id = ? AND user_id = ?;
 SELECT t.id, t.name, t.created_by, t.created_at, tm.role 
 FROM teams t
 JOIN team_members tm ON t.id = tm.team_id
-WHERE tm.user_id = ?;
\ No newline at end of file
+WHERE tm.user_id = ?;
+
+-- name: ListTeamMembers :many
+SELECT tm.user_id, u.email, tm.role, tm.joined_at
+FROM team_members tm
+JOIN users u ON u.id = tm.user_id
+WHERE tm.team_id = ?
+ORDER BY tm.joined_at, tm.user_id;
+
+-- name: UpdateTeamMemberRole :exec
+UPDATE team_members
+SET role = ?
+WHERE team_id = ? AND user_id = ?;
+
+-- name: RemoveTeamMember :exec
+DELETE FROM team_members
+WHERE team_id = ? AND user_id = ?;
+
+-- name: CountTeamOwners :one
+SELECT COUNT(*) FROM team_members
+WHERE team_id = ? AND role = 'owner'
+FOR UPDATE;
+
+-- name: UpdateTeamName :exec
+UPDATE teams
+SET name = ?
+WHERE id = ?;
+
+-- name: DeleteTeam :exec
+DELETE FROM teams WHERE id = ?;
+
+-- name: DeleteTeamMembers :execrows
+DELETE FROM team_members WHERE team_id = ?;
+
+-- name: LockTeam :one
+SELECT id FROM teams
+WHERE id = ?
+FOR UPDATE;
\ No newline at end of file
@@ -8,4 +8,21 @@ WHERE email = ? LIMIT 1;
 
 -- name: GetUserByID :one
 SELECT * FROM users 
-WHERE id = ? LIMIT 1;
\ No newline at end of file
+WHERE id = ? LIMIT 1;
+
+-- name: UpdateUserProfile :exec
+UPDATE users
+SET display_name = ?
+WHERE id = ?;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET password_hash = ?
+WHERE id = ?;
+
+-- name: UsersShareTeam :one
+SELECT EXISTS(
+    SELECT 1 FROM team_members a
+    JOIN team_members b ON a.team_id = b.team_id
+    WHERE a.user_id = ? AND b.user_id = ?
+) AS shared;
\ No newline at end of file
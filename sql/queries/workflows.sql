@@ -0,0 +1,23 @@
+-- name: ListTeamStatuses :many
+SELECT name FROM team_statuses
+WHERE team_id = ?
+ORDER BY position;
+
+-- name: ListTeamTransitions :many
+SELECT from_status, to_status FROM team_status_transitions
+WHERE team_id = ?
+ORDER BY from_status, to_status;
+
+-- name: CreateTeamStatus :exec
+INSERT INTO team_statuses (team_id, name, position)
+VALUES (?, ?, ?);
+
+-- name: CreateTeamTransition :exec
+INSERT INTO team_status_transitions (team_id, from_status, to_status)
+VALUES (?, ?, ?);
+
+-- name: DeleteTeamStatuses :exec
+DELETE FROM team_statuses WHERE team_id = ?;
+
+-- name: DeleteTeamTransitions :exec
+DELETE FROM team_status_transitions WHERE team_id = ?;
\ No newline at end of file
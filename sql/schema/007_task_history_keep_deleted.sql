@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE task_history DROP FOREIGN KEY fk_history_task_id;
+
+-- +goose Down
+DELETE FROM task_history WHERE task_id NOT IN (SELECT id FROM tasks);
+ALTER TABLE task_history
+    ADD CONSTRAINT fk_history_task_id FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE;
\ No newline at end of file
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE team_invitations (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    team_id BIGINT NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    user_id BIGINT NULL,
+    role ENUM('admin', 'member') NOT NULL DEFAULT 'member',
+    status ENUM('pending', 'accepted', 'declined') NOT NULL DEFAULT 'pending',
+    invited_by BIGINT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    responded_at TIMESTAMP NULL,
+
+    CONSTRAINT fk_invitations_team_id FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE,
+    CONSTRAINT fk_invitations_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_invitations_invited_by FOREIGN KEY (invited_by) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_invitations_team_email (team_id, email, status),
+    INDEX idx_invitations_email (email),
+    INDEX idx_invitations_user_status (user_id, status)
+);
+
+-- +goose Down
+DROP TABLE team_invitations;
\ No newline at end of file
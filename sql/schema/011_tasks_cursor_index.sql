@@ -0,0 +1,5 @@
+-- +goose Up
+CREATE INDEX idx_tasks_team_created ON tasks(team_id, created_at, id);
+
+-- +goose Down
+DROP INDEX idx_tasks_team_created ON tasks;
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- +goose Up
+CREATE FULLTEXT INDEX idx_tasks_fulltext ON tasks(title, description);
+
+-- +goose Down
+DROP INDEX idx_tasks_fulltext ON tasks;
\ No newline at end of file
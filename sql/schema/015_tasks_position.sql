@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE tasks ADD COLUMN position BIGINT NOT NULL DEFAULT 0;
+
+UPDATE tasks t
+JOIN (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY team_id, status ORDER BY created_at, id) * 1024 AS position
+    FROM tasks
+) ranked ON ranked.id = t.id
+SET t.position = ranked.position, t.updated_at = t.updated_at;
+
+CREATE INDEX idx_tasks_board ON tasks(team_id, status, position, id);
+
+-- +goose Down
+DROP INDEX idx_tasks_board ON tasks;
+ALTER TABLE tasks DROP COLUMN position;
\ No newline at end of file
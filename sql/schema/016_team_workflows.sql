@@ -0,0 +1,28 @@
+-- +goose Up
+-- A team without rows here uses the default workflow (todo, in_progress, done
+-- with any transition allowed), so existing teams need no seeding.
+CREATE TABLE team_statuses (
+    team_id BIGINT NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    position INT NOT NULL,
+    PRIMARY KEY (team_id, name),
+    CONSTRAINT fk_team_statuses_team_id FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE
+);
+
+CREATE TABLE team_status_transitions (
+    team_id BIGINT NOT NULL,
+    from_status VARCHAR(50) NOT NULL,
+    to_status VARCHAR(50) NOT NULL,
+    PRIMARY KEY (team_id, from_status, to_status),
+    CONSTRAINT fk_team_transitions_team_id FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE
+);
+
+ALTER TABLE tasks MODIFY status VARCHAR(50) NOT NULL DEFAULT 'todo';
+
+-- +goose Down
+-- Custom statuses do not fit the ENUM: their tasks go back to todo.
+UPDATE tasks SET status = 'todo', updated_at = updated_at
+WHERE status NOT IN ('todo', 'in_progress', 'done');
+ALTER TABLE tasks MODIFY status ENUM('todo', 'in_progress', 'done') NOT NULL DEFAULT 'todo';
+DROP TABLE team_status_transitions;
+DROP TABLE team_statuses;
\ No newline at end of file